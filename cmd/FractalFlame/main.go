@@ -1,17 +1,34 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"FractalFlame/internal/application"
+	"FractalFlame/internal/domain/errors"
 	"FractalFlame/internal/infrastructure/io"
 	"FractalFlame/pkg/logger"
 )
 
+// Команды: без имени команды (или render) выполняется рендер, tonemap заново обрабатывает сохраненную гистограмму,
+// merge складывает гистограммы, перечисленные после флагов.
 func main() {
-	config := flag.String("config", "", "path")
-	flag.Parse()
+	command, args := "render", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	config := flags.String("config", "", "path")
+	histogram := flags.String("histogram", "", "histogram file for the tonemap command")
+	resume := flags.String("resume", "", "checkpoint file to continue the render from")
+	quiet := flags.Bool("quiet", false, "do not show the render progress line")
+
+	_ = flags.Parse(args)
 
 	fileLogger := logger.NewFileLogger("logs.txt")
 	outputHandler := io.NewWriter(os.Stdout, fileLogger.Logger())
@@ -19,7 +36,43 @@ func main() {
 	defer fileLogger.Close()
 
 	app := application.NewApp(fileLogger.Logger(), outputHandler)
-	if err := app.Start(config); err != nil {
+	app.SetQuiet(*quiet)
+
+	ctx := interruptible(outputHandler)
+
+	var err error
+
+	switch command {
+	case "render":
+		err = app.Start(ctx, config, *resume)
+	case "tonemap":
+		err = app.ToneMapHistogram(config, *histogram)
+	case "merge":
+		err = app.MergeHistograms(config, flags.Args())
+	default:
+		err = errors.ErrUnknownCommand{Name: command}
+		outputHandler.Write("Unknown command", command)
+	}
+
+	if err != nil {
 		fileLogger.Logger().Error("Error happened while running the application", "error", err)
 	}
 }
+
+// interruptible - контекст, который отменяется по первому SIGINT или SIGTERM: рендер останавливается и сохраняет
+// частичное изображение. После первого сигнала обработка сбрасывается, и повторный Ctrl-C завершает процесс сразу.
+func interruptible(outputHandler *io.Output) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		signal.Reset(os.Interrupt, syscall.SIGTERM)
+		outputHandler.Write("Interrupted, saving the partial image. Press Ctrl-C again to abort")
+		cancel()
+	}()
+
+	return ctx
+}
@@ -20,21 +20,73 @@ type LinearTransformationsConfig struct {
 	EyeFish      bool `json:"EyeFish"`
 }
 
+// CustomTransformationConfig - описание пользовательского нелинейного преобразования на языке выражений,
+// каждая строка Code содержит присваивание вида x' = ... или y' = ....
+type CustomTransformationConfig struct {
+	Name   string             `json:"name"`
+	Code   []string           `json:"code"`
+	Params map[string]float64 `json:"params"`
+}
+
+// CameraConfig - параметры камеры: центр кадра, масштаб в пикселях на единицу (0 - по размеру изображения),
+// зум (каждая единица увеличивает изображение вдвое) и поворот в градусах.
+type CameraConfig struct {
+	Center [2]float64 `json:"center"`
+	Scale  float64    `json:"scale"`
+	Zoom   float64    `json:"zoom"`
+	Rotate float64    `json:"rotate"`
+}
+
 type Configuration struct {
 	Application struct {
-		Width              int     `json:"width"`
-		Height             int     `json:"height"`
-		StartingPoints     int     `json:"startingPoints"`
-		Iterations         int     `json:"iterations"`
-		SingleThread       bool    `json:"singleThread"`
-		Gamma              bool    `json:"gamma"`
-		GammaCoeff         float64 `json:"gammaCoeff"`
-		NumWorkers         int     `json:"numWorkers"`
-		HorizontalSymmetry bool    `json:"horizontalSymmetry"`
-		VerticalSymmetry   bool    `json:"verticalSymmetry"`
-		Format             string  `json:"format"`
+		Width              int          `json:"width"`
+		Height             int          `json:"height"`
+		StartingPoints     int          `json:"startingPoints"`
+		Iterations         int          `json:"iterations"`
+		SingleThread       bool         `json:"singleThread"`
+		Gamma              bool         `json:"gamma"`
+		GammaCoeff         float64      `json:"gammaCoeff"`
+		NumWorkers         int          `json:"numWorkers"`
+		HorizontalSymmetry bool         `json:"horizontalSymmetry"`
+		VerticalSymmetry   bool         `json:"verticalSymmetry"`
+		Symmetry           int          `json:"symmetry"`
+		Tileable           bool         `json:"tileable"`
+		WallpaperGroup     string       `json:"wallpaperGroup"`
+		LatticeScale       float64      `json:"latticeScale"`
+		Hyperbolic         [2]int       `json:"hyperbolic"`
+		Projection         string       `json:"projection"`
+		Stereo             string       `json:"stereo"`
+		EyeSeparation      float64      `json:"eyeSeparation"`
+		FilterRadius       float64      `json:"filterRadius"`
+		Format             string       `json:"format"`
+		Background         string       `json:"background"`
+		PremultipliedAlpha bool         `json:"premultipliedAlpha"`
+		BitDepth           int          `json:"bitDepth"`
+		Exposure           float64      `json:"exposure"`
+		PostAffine         bool         `json:"postAffine"`
+		Camera             CameraConfig `json:"camera"`
+		AutoFrame          bool         `json:"autoFrame"`
+		CamPitch           float64      `json:"camPitch"`
+		CamYaw             float64      `json:"camYaw"`
+		CamPerspective     float64      `json:"camPerspective"`
+		CamZPos            float64      `json:"camZpos"`
+		CamDOF             float64      `json:"camDof"`
+		CamFocus           float64      `json:"camFocus"`
+		Seed               uint64       `json:"seed"`
+		Genome             string       `json:"genome"`
+		GenomeOutput       string       `json:"genomeOutput"`
+		HistogramOutput    string       `json:"histogramOutput"`
+		Checkpoint         string       `json:"checkpoint"`
+		CheckpointInterval float64      `json:"checkpointInterval"`
+		TimeBudget         float64      `json:"timeBudget"`
+		SamplesPerPixel    float64      `json:"samplesPerPixel"`
+		NoiseThreshold     float64      `json:"noiseThreshold"`
+		Preview            string       `json:"preview"`
+		PreviewInterval    float64      `json:"previewInterval"`
+		MemoryLimit        float64      `json:"memoryLimit"`
 	} `json:"Application"`
-	ListOfTransformations LinearTransformationsConfig `json:"LinearTransformations"`
+	ListOfTransformations LinearTransformationsConfig  `json:"LinearTransformations"`
+	CustomTransformations []CustomTransformationConfig `json:"CustomTransformations"`
 }
 
 func Read(filePath string) (*Configuration, error) {
@@ -52,9 +104,15 @@ func Read(filePath string) (*Configuration, error) {
 		return nil, err
 	}
 
-	if config.Application.Height == 0 || config.Application.Width == 0 || config.Application.StartingPoints == 0 {
+	if config.Application.Height == 0 || config.Application.Width == 0 {
 		return nil, errors.ErrZeroSizeMatrix{}
 	}
 
+	// Без ограничения по времени, плотности или шуму число стартовых точек - единственное условие остановки рендера.
+	budget := config.Application.TimeBudget > 0 || config.Application.SamplesPerPixel > 0 || config.Application.NoiseThreshold > 0
+	if config.Application.StartingPoints == 0 && !budget {
+		return nil, errors.ErrNoStopCondition{}
+	}
+
 	return &config, nil
 }
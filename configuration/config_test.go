@@ -0,0 +1,23 @@
+package configuration_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"FractalFlame/configuration"
+	domainErrors "FractalFlame/internal/domain/errors"
+)
+
+func TestRead_RejectsRenderWithoutStopCondition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"Application": {"width": 64, "height": 48, "iterations": 100}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := configuration.Read(path)
+	if !errors.As(err, &domainErrors.ErrNoStopCondition{}) {
+		t.Errorf("error %v, want no stop condition", err)
+	}
+}
@@ -1,40 +1,81 @@
 package application
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"image"
 	"log/slog"
-	"os"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
 
 	"FractalFlame/configuration"
 	"FractalFlame/internal/domain"
 	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/expression"
 	"FractalFlame/internal/domain/generator"
+	"FractalFlame/internal/domain/genome"
 	"FractalFlame/internal/domain/savers"
 	"FractalFlame/internal/domain/transformations"
-	"FractalFlame/internal/infrastructure/io"
 )
 
+// outputName - имя сохраняемого изображения без расширения.
+const outputName = "FractalFlame"
+
 type fractalBuilder interface {
-	Render(im *domain.ImageMatrix)
+	RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int
 }
 
 type saver interface {
-	Save(img image.Image) error
+	Save(img image.Image, name string, meta savers.Metadata) error
 }
 
 type outputHandler interface {
 	Write(messages ...interface{})
+	Progress(line string)
+	FinishProgress()
 }
 
 type Application struct {
-	imageMatrix     *domain.ImageMatrix
-	symmetry        symmetryFlags
-	correction      bool
-	correctionCoeff float64
-	outputHandler   outputHandler
-	logger          *slog.Logger
-	saver           saver
-	fractalBuilder  fractalBuilder
+	imageMatrix        *domain.ImageMatrix
+	symmetry           symmetryFlags
+	correction         bool
+	correctionCoeff    float64
+	exposure           float64
+	outputHandler      outputHandler
+	logger             *slog.Logger
+	saver              saver
+	fractalBuilder     fractalBuilder
+	genomePath         string
+	genomeOutput       string
+	histogramOutput    string
+	checkpointPath     string
+	checkpointInterval time.Duration
+	completed          int
+	interrupted        bool
+	timeBudget         time.Duration
+	targetSamples      float64
+	noiseThreshold     float64
+	noise              float64
+	snapshot           *domain.Snapshot
+	previewPath        string
+	previewInterval    time.Duration
+	previews           previewState
+	memoryLimit        int64
+	renderStart        time.Time
+	renderTime         time.Duration
+	quiet              bool
+	progress           *generator.Progress
+	postAffine         bool
+	autoFrame          bool
+	symmetryOrder      int
+	filterRadius       float64
+	wallpaperGroup     string
+	latticeScale       float64
+	hyperbolic         [2]int
+	customNames        []string
 }
 
 type symmetryFlags struct {
@@ -46,48 +87,225 @@ func NewApp(logger *slog.Logger, handler outputHandler) *Application {
 	return &Application{logger: logger, outputHandler: handler}
 }
 
+// SetQuiet - отключает строку состояния рендера, например для запуска из скриптов.
+func (a *Application) SetQuiet(quiet bool) {
+	a.quiet = quiet
+}
+
 func (a *Application) setUp(filePath *string) error {
 	config, err := configuration.Read(*filePath)
 	if err != nil {
 		return errors.ErrReadingConfig{Err: err}
 	}
 
-	a.outputHandler = io.NewWriter(os.Stdout, a.logger)
+	if err := a.createImageMatrix(config); err != nil {
+		return err
+	}
+
+	a.setBudget(config)
+
+	a.imageMatrix.Seed = config.Application.Seed
+	if a.imageMatrix.Seed == 0 {
+		a.imageMatrix.Seed = rand.Uint64() //nolint
+	}
+
+	a.imageMatrix.SetCamera(domain.Camera{
+		CenterX: config.Application.Camera.Center[0],
+		CenterY: config.Application.Camera.Center[1],
+		Scale:   config.Application.Camera.Scale,
+		Zoom:    config.Application.Camera.Zoom,
+		Rotate:  config.Application.Camera.Rotate,
+	})
 
-	a.imageMatrix = domain.NewImageMatrix(config.Application.Width, config.Application.Height,
-		config.Application.StartingPoints, config.Application.Iterations)
 	a.symmetry = symmetryFlags{
 		xSymmetry: config.Application.HorizontalSymmetry,
 		ySymmetry: config.Application.VerticalSymmetry,
 	}
 
+	a.postAffine = config.Application.PostAffine
+	a.autoFrame = config.Application.AutoFrame
+	a.symmetryOrder = config.Application.Symmetry
+	a.imageMatrix.Tileable = config.Application.Tileable
+	a.wallpaperGroup = config.Application.WallpaperGroup
+	a.latticeScale = config.Application.LatticeScale
+	a.hyperbolic = config.Application.Hyperbolic
+	a.genomePath = config.Application.Genome
+	a.genomeOutput = config.Application.GenomeOutput
+	a.histogramOutput = config.Application.HistogramOutput
+	a.checkpointPath = config.Application.Checkpoint
+	a.checkpointInterval = time.Duration(config.Application.CheckpointInterval * float64(time.Second))
+
+	if a.checkpointInterval <= 0 {
+		a.checkpointInterval = defaultCheckpointInterval
+	}
+
+	if err := a.setUpOutput(config); err != nil {
+		return err
+	}
+
+	a.setRenderer(config.Application.SingleThread, config.Application.NumWorkers)
+	a.validateSetOfLinearTransformations(config.ListOfTransformations)
+
+	if err := a.registerCustomTransformations(config.CustomTransformations); err != nil {
+		return err
+	}
+
+	if a.memoryLimit > 0 {
+		return a.checkTiled()
+	}
+
+	return nil
+}
+
+// createImageMatrix - создает буфер изображения с учетом проекции и стереопары, которые меняют его размер,
+// и задает трехмерную камеру.
+func (a *Application) createImageMatrix(config *configuration.Configuration) error {
+	projection, err := domain.ParseProjection(config.Application.Projection)
+	if err != nil {
+		return err
+	}
+
+	width, height := domain.ProjectionResolution(projection, config.Application.Width, config.Application.Height)
+
+	var stereo domain.StereoLayout
+
+	if config.Application.Stereo != "" {
+		if stereo, err = domain.ParseStereoLayout(config.Application.Stereo); err != nil {
+			return err
+		}
+
+		width, height = domain.StereoResolution(stereo, width, height)
+	}
+
+	a.memoryLimit = int64(config.Application.MemoryLimit * megabyte)
+
+	if a.memoryLimit > 0 {
+		a.imageMatrix = domain.NewTiledImageMatrix(width, height, config.Application.StartingPoints, config.Application.Iterations)
+	} else {
+		a.imageMatrix = domain.NewImageMatrix(width, height, config.Application.StartingPoints, config.Application.Iterations)
+	}
+
+	a.imageMatrix.SetProjection(projection)
+	a.setCamera3D(config)
+
+	if stereo != 0 {
+		return a.imageMatrix.SetStereo(config.Application.EyeSeparation, stereo)
+	}
+
+	return nil
+}
+
+// setCamera3D - создает камеру для 3D пламени, если задан хотя бы один из ее параметров.
+func (a *Application) setCamera3D(config *configuration.Configuration) {
+	cam := &config.Application
+	if cam.CamPitch == 0 && cam.CamYaw == 0 && cam.CamPerspective == 0 && cam.CamZPos == 0 && cam.CamDOF == 0 {
+		return
+	}
+
+	a.imageMatrix.Camera3D = domain.NewCamera3D(cam.CamPitch, cam.CamYaw, cam.CamPerspective, cam.CamZPos)
+	a.imageMatrix.Camera3D.DOF = cam.CamDOF
+	a.imageMatrix.Camera3D.Focus = cam.CamFocus
+}
+
+// setUpOutput - задает параметры, которые применяются после рендера: гамма-коррекцию, фильтр, глубину цвета,
+// формат и фон. Их же использует команда tonemap.
+func (a *Application) setUpOutput(config *configuration.Configuration) error {
 	a.correction = config.Application.Gamma
 	a.correctionCoeff = config.Application.GammaCoeff
+	a.filterRadius = config.Application.FilterRadius
+	a.exposure = config.Application.Exposure
+
+	if a.exposure <= 0 {
+		a.exposure = 1
+	}
+
+	if err := a.setBitDepth(config); err != nil {
+		return err
+	}
+
 	a.setSaver(config.Application.Format)
-	a.setRenderer(config.Application.SingleThread, config.Application.NumWorkers)
-	a.validateSetOfLinearTransformations(config.ListOfTransformations)
+
+	return a.setBackground(config)
+}
+
+// setBackground - задает фон изображения. В JPEG и HDR-форматах нет альфа-канала, поэтому прозрачный фон
+// для них заменяется черным, а цвет и градиент накладываются так же, как для PNG.
+func (a *Application) setBackground(config *configuration.Configuration) error {
+	background, err := domain.ParseBackground(config.Application.Background)
+	if err != nil {
+		return err
+	}
+
+	switch config.Application.Format {
+	case "JPEG", "PFM", "HDR":
+		if background != nil && background.Transparent {
+			background = nil
+		}
+	}
+
+	a.imageMatrix.Background = background
+	a.imageMatrix.PremultipliedAlpha = config.Application.PremultipliedAlpha
 
 	return nil
 }
 
+// setSaver - выбирает формат сохранения: JPEG, линейные HDR-форматы PFM и HDR (Radiance RGBE) или PNG,
+// который используется и для неизвестных значений.
 func (a *Application) setSaver(format string) {
-	if format == "JPEG" {
+	switch format {
+	case "JPEG":
 		a.saver = &savers.JpegSaver{}
+	case "PFM":
+		a.saver = &savers.PfmSaver{}
+	case "HDR":
+		a.saver = &savers.HdrSaver{}
+	default:
+		a.saver = &savers.PngSaver{BitDepth: a.imageMatrix.BitDepth}
+	}
+}
 
-		return
+// linearOutput - сохраняется ли результат в HDR-формат с линейными данными.
+func (a *Application) linearOutput() bool {
+	return a.imageMatrix.BitDepth == domain.FloatBitDepth
+}
+
+// setBitDepth - задает глубину цвета результата: 8 (по умолчанию) или 16 бит на канал. JPEG хранит только
+// 8 бит, а HDR-форматы - числа с плавающей точкой, поэтому для них параметр не используется.
+func (a *Application) setBitDepth(config *configuration.Configuration) error {
+	bitDepth := config.Application.BitDepth
+
+	switch bitDepth {
+	case 0:
+		bitDepth = 8
+	case 8, 16:
+	default:
+		return errors.ErrBitDepth{Depth: bitDepth}
+	}
+
+	switch config.Application.Format {
+	case "JPEG":
+		bitDepth = 8
+	case "PFM", "HDR":
+		bitDepth = domain.FloatBitDepth
 	}
 
-	a.saver = &savers.PngSaver{}
+	a.imageMatrix.BitDepth = bitDepth
+
+	return nil
 }
 
 func (a *Application) setRenderer(singleThread bool, workers int) {
+	if !a.quiet {
+		a.progress = &generator.Progress{Report: a.showProgress, Interval: progressInterval}
+	}
+
 	if singleThread {
-		a.fractalBuilder = &generator.SingleThreadGenerator{}
+		a.fractalBuilder = &generator.SingleThreadGenerator{Progress: a.progress}
 
 		return
 	}
 
-	a.fractalBuilder = &generator.MultiThreadGenerator{NumWorkers: workers}
+	a.fractalBuilder = &generator.MultiThreadGenerator{NumWorkers: workers, Progress: a.progress}
 }
 
 func (a *Application) validateSetOfLinearTransformations(trConfig configuration.LinearTransformationsConfig) {
@@ -136,19 +354,195 @@ func (a *Application) validateSetOfLinearTransformations(trConfig configuration.
 	a.imageMatrix.NonLinearTransformations = functions
 }
 
-func (a *Application) Start(source *string) error {
-	if err := a.setUp(source); err != nil {
-		return errors.ErrReadingConfig{Err: err}
+// registerCustomTransformations - компилирует пользовательские преобразования из конфигурации, регистрирует их
+// рядом со встроенными и добавляет в набор нелинейных преобразований.
+func (a *Application) registerCustomTransformations(custom []configuration.CustomTransformationConfig) error {
+	for _, trConfig := range custom {
+		variation, err := expression.Compile(strings.Join(trConfig.Code, "\n"), trConfig.Params)
+		if err != nil {
+			return errors.ErrCustomTransformation{Name: trConfig.Name, Err: err}
+		}
+
+		if err := transformations.Register(trConfig.Name, variation.Transform); err != nil {
+			return errors.ErrCustomTransformation{Name: trConfig.Name, Err: err}
+		}
+
+		a.customNames = append(a.customNames, trConfig.Name)
+
+		a.imageMatrix.NonLinearTransformations = append(a.imageMatrix.NonLinearTransformations, variation.Transform)
 	}
 
-	if len(a.imageMatrix.NonLinearTransformations) == 0 {
+	return nil
+}
+
+// unregisterCustomTransformations - удаляет из реестра пользовательские преобразования, зарегистрированные
+// при настройке, чтобы следующий запуск в том же процессе мог зарегистрировать их заново.
+func (a *Application) unregisterCustomTransformations() {
+	for _, name := range a.customNames {
+		transformations.Unregister(name)
+	}
+
+	a.customNames = nil
+}
+
+// prepareTransformations - загружает преобразования из генома, если он указан, или генерирует случайные
+// (при включенном postAffine вместе со случайными пост-аффинными преобразованиями). Симметрия, группа обоев
+// и гиперболическая мозаика из конфигурации добавляются, если геном не задает свои.
+func (a *Application) prepareTransformations() error {
+	if a.genomePath == "" {
+		a.imageMatrix.GenerateAffineTransformations()
+
+		if a.postAffine {
+			a.imageMatrix.GeneratePostAffineTransformations()
+		}
+	} else {
+		g, err := genome.Load(a.genomePath)
+		if err != nil {
+			return err
+		}
+
+		if err := g.Apply(a.imageMatrix); err != nil {
+			return err
+		}
+	}
+
+	if a.imageMatrix.Symmetry == 0 {
+		a.imageMatrix.AddSymmetry(a.symmetryOrder)
+	}
+
+	if group, _ := a.imageMatrix.WallpaperGroup(); group == "" && a.wallpaperGroup != "" {
+		if err := a.imageMatrix.SetWallpaperGroup(a.wallpaperGroup, a.latticeScale); err != nil {
+			return err
+		}
+	}
+
+	if a.imageMatrix.HyperbolicTiling() == nil && a.hyperbolic != [2]int{} {
+		if err := a.imageMatrix.SetHyperbolicTiling(a.hyperbolic[0], a.hyperbolic[1]); err != nil {
+			return err
+		}
+	}
+
+	if a.imageMatrix.NeedsNonLinearTransformations() && len(a.imageMatrix.NonLinearTransformations) == 0 {
 		a.outputHandler.Write("Unable to generate image without any non linear transformations")
 		return errors.ErrZeroSizeMatrix{}
 	}
 
-	a.imageMatrix.GenerateAffineTransformations()
+	return nil
+}
+
+// saveImage - сохраняет изображение, кубическая карта сохраняется шестью файлами FractalFlame_<грань>.
+func (a *Application) saveImage() error {
+	return saveMatrix(a.imageMatrix, a.saver, outputName, a.metadata())
+}
+
+// saveMatrix - сохраняет буфер im после тональной компрессии под именем name, кубическая карта сохраняется
+// шестью файлами граней.
+func saveMatrix(im *domain.ImageMatrix, s saver, name string, meta savers.Metadata) error {
+	if im.Projection() != domain.ProjectionCubemap {
+		return s.Save(im.ConvertToImage(), name, meta)
+	}
+
+	for i, face := range im.ConvertCubemap() {
+		if err := s.Save(face, name+"_"+domain.CubemapFaces[i], meta); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// metadata - собирает метаданные для сохраняемого изображения: seed, камеру, проекцию и параметры стереопары,
+// с которыми оно отрисовано, а для прерванного рендера - сколько стартовых точек и сэмплов в нем учтено.
+func (a *Application) metadata() savers.Metadata {
+	meta := savers.Metadata{"Seed": strconv.FormatUint(a.imageMatrix.Seed, 10)}
+
+	if camera, err := json.Marshal(genome.FromMatrix(a.imageMatrix).Camera); err == nil {
+		meta["Camera"] = string(camera)
+	}
+
+	if projection := a.imageMatrix.Projection(); projection != domain.ProjectionPlane {
+		meta["Projection"] = projection.String()
+	}
+
+	if stereo := a.imageMatrix.Stereo(); stereo != nil {
+		meta["Stereo"] = stereo.Layout.String() + ", separation " + strconv.FormatFloat(stereo.Separation, 'g', -1, 64)
+	}
+
+	if a.interrupted {
+		of := ""
+		if total := a.imageMatrix.StartingPoints; total != unlimitedStartingPoints {
+			of = fmt.Sprintf(" of %d", total)
+		}
+
+		meta["Partial"] = fmt.Sprintf("render interrupted after %d%s starting points, %d samples",
+			a.completed, of, a.completed*a.imageMatrix.Iterations)
+	}
+
+	if a.budgeted() {
+		meta["Quality"] = fmt.Sprintf("%s, %d starting points, %d samples, %.1f s",
+			a.quality(), a.completed, a.completed*a.imageMatrix.Iterations, a.renderTime.Seconds())
+	}
+
+	return meta
+}
+
+// Start - рендер по конфигурации source. Если задан resumePath, рендер продолжается с контрольной точки.
+// При отмене ctx рендер останавливается, и сохраняется изображение по уже обработанным стартовым точкам.
+// Пользовательские преобразования из конфигурации остаются в реестре только до возврата из Start.
+func (a *Application) Start(ctx context.Context, source *string, resumePath string) error {
+	defer a.unregisterCustomTransformations()
+
+	if err := a.setUp(source); err != nil {
+		return errors.ErrReadingConfig{Err: err}
+	}
+
+	checkpoint, err := a.loadCheckpoint(resumePath)
+	if err != nil {
+		return err
+	}
+
+	if err := a.prepareTransformations(); err != nil {
+		return err
+	}
+
+	from, err := a.resume(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if checkpoint == nil && a.autoFrame {
+		a.imageMatrix.AutoFrame()
+	}
+
+	a.outputHandler.Write("Seed:", a.imageMatrix.Seed)
+
+	if a.memoryLimit > 0 {
+		err = a.renderTiled(ctx)
+	} else {
+		err = a.renderImage(ctx, from)
+	}
+
+	if err != nil {
+		return err
+	}
 
-	a.fractalBuilder.Render(a.imageMatrix)
+	if a.genomeOutput != "" {
+		if err := genome.FromMatrix(a.imageMatrix).Save(a.genomeOutput); err != nil {
+			a.outputHandler.Write("Error occurred saving genome")
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderImage - рендер в буфер всего изображения: стартовые точки, зеркальная симметрия, сохранение
+// гистограммы, тональная компрессия и сохранение результата.
+func (a *Application) renderImage(ctx context.Context, from int) error {
+	if err := a.render(ctx, from); err != nil {
+		return err
+	}
 
 	if a.symmetry.xSymmetry {
 		a.imageMatrix.ReflectHorizontally()
@@ -158,19 +552,51 @@ func (a *Application) Start(source *string) error {
 		a.imageMatrix.ReflectVertically()
 	}
 
+	if a.histogramOutput != "" {
+		if err := a.saveHistogram(); err != nil {
+			a.outputHandler.Write("Error occurred saving histogram")
+
+			return err
+		}
+	}
+
+	return a.develop()
+}
+
+// gamma - показатель тональной компрессии для экрана: 0 без гамма-коррекции.
+func (a *Application) gamma() float64 {
 	if a.correction {
-		a.imageMatrix.Correction(a.correctionCoeff)
+		return a.correctionCoeff
 	}
 
-	img := a.imageMatrix.ConvertToImage()
+	return 0
+}
+
+// toneMap - тональная компрессия буфера im: HDR-форматы получают линейные данные с экспозицией exposure,
+// остальные - логарифмическую плотность с гамма-коррекцией.
+func (a *Application) toneMap(im *domain.ImageMatrix) {
+	if a.linearOutput() {
+		im.ToneMapLinear(a.exposure)
+
+		return
+	}
+
+	im.ToneMap(a.gamma())
+}
+
+// develop - тональная компрессия, фильтр и сохранение накопленной гистограммы.
+func (a *Application) develop() error {
+	a.toneMap(a.imageMatrix)
+
+	a.imageMatrix.Filter(a.filterRadius)
 
-	if err := a.saver.Save(img); err != nil {
+	if err := a.saveImage(); err != nil {
 		a.outputHandler.Write("Error occurred saving image restart please")
 
 		return errors.ErrSavingImage{Err: err}
 	}
 
-	a.outputHandler.Write("Изображение сохранено как FractalFlame")
+	a.outputHandler.Write("Изображение сохранено как", outputName)
 
 	return nil
 }
@@ -0,0 +1,85 @@
+package application
+
+import (
+	"context"
+	stdio "io"
+	"log/slog"
+
+	"FractalFlame/configuration"
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/savers"
+	"FractalFlame/internal/infrastructure/io"
+)
+
+// FractalBuilder - генератор, который тесты подставляют вместо настоящего.
+type FractalBuilder = fractalBuilder
+
+// NewRenderApp - приложение, которое рендерит im генератором builder с ограничениями и форматом результата
+// из config, как после setUp, но без чтения файла конфигурации и без вывода.
+func NewRenderApp(im *domain.ImageMatrix, builder FractalBuilder, config *configuration.Configuration) *Application {
+	logger := slog.New(slog.NewTextHandler(stdio.Discard, nil))
+
+	a := NewApp(logger, io.NewWriter(stdio.Discard, logger))
+	a.imageMatrix = im
+	a.fractalBuilder = builder
+	a.setBudget(config)
+	a.setSaver(config.Application.Format)
+
+	return a
+}
+
+// SetOutput - подменяет вывод сообщений приложения.
+func (a *Application) SetOutput(handler outputHandler) {
+	a.outputHandler = handler
+}
+
+func (a *Application) Render(ctx context.Context, from int) error {
+	return a.render(ctx, from)
+}
+
+// RenderImage - рендер с начала, тональная компрессия и сохранение результата в текущий каталог.
+func (a *Application) RenderImage(ctx context.Context) error {
+	return a.renderImage(ctx, 0)
+}
+
+func (a *Application) Metadata() savers.Metadata {
+	return a.metadata()
+}
+
+func (a *Application) Completed() int {
+	return a.completed
+}
+
+// PreviewAfter - вызывает preview так, будто рендер только что закончил порцию и готово completed стартовых
+// точек.
+func (a *Application) PreviewAfter(completed int) {
+	a.completed = completed
+	a.preview()
+}
+
+// HoldPreview - помечает превью как сохраняемое (busy) или освобождает его.
+func (a *Application) HoldPreview(busy bool) {
+	a.previews.busy.Store(busy)
+}
+
+func (a *Application) WaitPreviews() {
+	a.previews.wg.Wait()
+}
+
+// NewTiledApp - приложение, которое рендерит im по полосам генератором builder в пределах memoryLimit байт
+// с фильтром радиуса filterRadius.
+func NewTiledApp(im *domain.ImageMatrix, builder FractalBuilder, memoryLimit int64, filterRadius float64) *Application {
+	logger := slog.New(slog.NewTextHandler(stdio.Discard, nil))
+
+	a := NewApp(logger, io.NewWriter(stdio.Discard, logger))
+	a.imageMatrix = im
+	a.fractalBuilder = builder
+	a.memoryLimit = memoryLimit
+	a.filterRadius = filterRadius
+
+	return a
+}
+
+func (a *Application) RenderTiled(ctx context.Context) error {
+	return a.renderTiled(ctx)
+}
@@ -0,0 +1,112 @@
+package application
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"FractalFlame/configuration"
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/genome"
+)
+
+// saveHistogram - записывает накопленную гистограмму в файл histogramOutput, чтобы потом заново выполнить
+// тональную компрессию командой tonemap.
+func (a *Application) saveHistogram() error {
+	file, err := os.Create(a.histogramOutput)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+
+	if err := a.imageMatrix.WriteHistogram(out, genome.FromMatrix(a.imageMatrix).Hash()); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
+
+// loadHistogram - читает файл гистограммы.
+func loadHistogram(path string) (*domain.ImageMatrix, domain.HistogramHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, domain.HistogramHeader{}, err
+	}
+	defer file.Close()
+
+	return domain.ReadHistogram(bufio.NewReader(file))
+}
+
+// ToneMapHistogram - команда tonemap: загружает гистограмму, сохраненную при рендере, и выполняет тональную
+// компрессию, фильтр и сохранение с параметрами вывода из конфигурации (gamma, gammaCoeff, filterRadius,
+// format, bitDepth, background) без повторного рендера.
+func (a *Application) ToneMapHistogram(source *string, histogramPath string) error {
+	config, err := configuration.Read(*source)
+	if err != nil {
+		return errors.ErrReadingConfig{Err: err}
+	}
+
+	if a.imageMatrix, _, err = loadHistogram(histogramPath); err != nil {
+		return err
+	}
+
+	if err := a.setUpOutput(config); err != nil {
+		return errors.ErrReadingConfig{Err: err}
+	}
+
+	return a.develop()
+}
+
+// MergeHistograms - команда merge: складывает гистограммы одного пламени, отрисованные отдельными запусками
+// (например, с разными seed на разных машинах), сохраняет сумму в histogramOutput, если он задан, и выполняет
+// тональную компрессию результата с параметрами вывода из конфигурации.
+func (a *Application) MergeHistograms(source *string, paths []string) error {
+	config, err := configuration.Read(*source)
+	if err != nil {
+		return errors.ErrReadingConfig{Err: err}
+	}
+
+	files := make([]*os.File, 0, len(paths))
+	readers := make([]io.Reader, 0, len(paths))
+
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, file)
+		readers = append(readers, bufio.NewReader(file))
+	}
+
+	if a.imageMatrix, _, err = domain.MergeHistograms(readers...); err != nil {
+		if mismatch, ok := err.(errors.ErrHistogramMismatch); ok {
+			a.outputHandler.Write("Histogram", paths[mismatch.Index], "is incompatible with", paths[0]+":", mismatch.Field, "differs")
+		}
+
+		return err
+	}
+
+	a.outputHandler.Write("Merged histograms:", len(paths))
+
+	if err := a.setUpOutput(config); err != nil {
+		return errors.ErrReadingConfig{Err: err}
+	}
+
+	if a.histogramOutput = config.Application.HistogramOutput; a.histogramOutput != "" {
+		if err := a.saveHistogram(); err != nil {
+			return err
+		}
+	}
+
+	return a.develop()
+}
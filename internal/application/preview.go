@@ -0,0 +1,90 @@
+package application
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"FractalFlame/internal/domain/savers"
+)
+
+// previewFractions - доли рендера, после которых сохраняется превью.
+var previewFractions = []float64{0.01, 0.05, 0.25}
+
+// previewState - состояние сохранения превью. Одновременно сохраняется не больше одного превью: если
+// предыдущее еще не готово, очередное откладывается до следующей порции. next - номер доли из previewFractions,
+// превью для которой еще не сохранялось.
+type previewState struct {
+	wg   sync.WaitGroup
+	busy atomic.Bool
+	next int
+	last time.Time
+}
+
+// preview - сохраняет превью, если рендер прошел очередную долю из previewFractions или с прошлого превью
+// прошло previewInterval. Доля считается пройденной, только когда превью для нее запущено. Вызывается между
+// порциями, пока генераторы стоят: гистограмма копируется синхронно, и на время копирования рендер ждет,
+// а тональная компрессия, фильтр и запись идут в отдельной горутине, не задерживая следующую порцию.
+func (a *Application) preview() {
+	fraction := a.renderFraction()
+	next := a.previews.next
+
+	for next < len(previewFractions) && fraction >= previewFractions[next] {
+		next++
+	}
+
+	due := next > a.previews.next || a.previewInterval > 0 && time.Since(a.previews.last) >= a.previewInterval
+	if !due || !a.previews.busy.CompareAndSwap(false, true) {
+		return
+	}
+
+	a.previews.next = next
+	a.previews.last = time.Now()
+
+	snapshot := a.imageMatrix.CopyHistogram()
+	snapshot.BitDepth = 8
+
+	meta := savers.Metadata{
+		"Seed":    strconv.FormatUint(a.imageMatrix.Seed, 10),
+		"Preview": fmt.Sprintf("%d starting points, %.1f samples per pixel", a.completed, snapshot.SamplesPerPixel()),
+	}
+
+	// Превью всегда 8-битный PNG с гамма-коррекцией для экрана, даже если результат сохраняется в HDR.
+	gamma := a.gamma()
+
+	a.previews.wg.Add(1)
+
+	go func() {
+		defer a.previews.wg.Done()
+		defer a.previews.busy.Store(false)
+
+		snapshot.ToneMap(gamma)
+		snapshot.Filter(a.filterRadius)
+
+		if err := saveMatrix(snapshot, &savers.PngSaver{}, a.previewPath, meta); err != nil {
+			a.logger.Error("Error occurred saving preview", "error", err)
+		}
+	}()
+}
+
+// renderFraction - доля выполненного рендера: по числу стартовых точек, времени или плотности, смотря что
+// ближе к завершению. Без ограничений, кроме шума, долю оценить нельзя, и она остается нулевой.
+func (a *Application) renderFraction() float64 {
+	var fraction float64
+
+	if total := a.imageMatrix.StartingPoints; total != unlimitedStartingPoints {
+		fraction = float64(a.completed) / float64(total)
+	}
+
+	if a.timeBudget > 0 {
+		fraction = max(fraction, time.Since(a.renderStart).Seconds()/a.timeBudget.Seconds())
+	}
+
+	if a.targetSamples > 0 {
+		fraction = max(fraction, a.imageMatrix.SamplesPerPixel()/a.targetSamples)
+	}
+
+	return fraction
+}
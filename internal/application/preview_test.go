@@ -0,0 +1,120 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"FractalFlame/configuration"
+	"FractalFlame/internal/application"
+	"FractalFlame/internal/domain"
+)
+
+// previewApp - приложение с рендером из startingPoints стартовых точек и превью в path.
+func previewApp(t *testing.T, builder application.FractalBuilder, startingPoints int) (app *application.Application, path string) {
+	t.Helper()
+
+	path = filepath.Join(t.TempDir(), "preview")
+
+	config := &configuration.Configuration{}
+	config.Application.StartingPoints = startingPoints
+	config.Application.Preview = path
+
+	return application.NewRenderApp(domain.NewImageMatrix(2, 2, startingPoints, 10), builder, config), path
+}
+
+// takePreview - сохранено ли превью с прошлой проверки. Найденный файл удаляется.
+func takePreview(t *testing.T, app *application.Application, path string) bool {
+	t.Helper()
+
+	app.WaitPreviews()
+
+	err := os.Remove(path + ".png")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+
+	return err == nil
+}
+
+func TestPreview_SavedOncePerReachedFraction(t *testing.T) {
+	app, path := previewApp(t, countingBuilder{}, 100)
+
+	steps := []struct {
+		completed int
+		saved     bool
+	}{
+		{completed: 0, saved: false},
+		{completed: 2, saved: true},
+		{completed: 3, saved: false},
+		{completed: 5, saved: true},
+		{completed: 30, saved: true},
+		{completed: 60, saved: false},
+	}
+
+	for _, step := range steps {
+		app.PreviewAfter(step.completed)
+
+		if saved := takePreview(t, app, path); saved != step.saved {
+			t.Errorf("after %d of 100 starting points preview saved: %v, want %v", step.completed, saved, step.saved)
+		}
+	}
+}
+
+func TestPreview_FractionReachedWhileBusyIsNotSkipped(t *testing.T) {
+	app, path := previewApp(t, countingBuilder{}, 100)
+
+	app.HoldPreview(true)
+	app.PreviewAfter(2)
+
+	if takePreview(t, app, path) {
+		t.Fatal("preview saved while the previous one is still being written")
+	}
+
+	app.HoldPreview(false)
+	app.PreviewAfter(3)
+
+	if !takePreview(t, app, path) {
+		t.Error("preview for 1% was dropped because the previous preview was busy")
+	}
+}
+
+// rangeRecorder - генератор, который запоминает размеры порций.
+type rangeRecorder struct {
+	countingBuilder
+
+	mutex   sync.Mutex
+	batches []int
+}
+
+func (r *rangeRecorder) RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int {
+	r.mutex.Lock()
+	r.batches = append(r.batches, to-from)
+	r.mutex.Unlock()
+
+	return r.countingBuilder.RenderRange(ctx, im, from, to)
+}
+
+func TestRender_WithPreviewRendersInBatchesAndSavesPreview(t *testing.T) {
+	// Порция - процент стартовых точек, но не меньше 4 * NumCPU.
+	total := 1000 * runtime.NumCPU()
+	builder := &rangeRecorder{}
+	app, path := previewApp(t, builder, total)
+
+	if err := app.Render(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if app.Completed() != total || len(builder.batches) != 100 {
+		t.Errorf("%d starting points in %d batches, want all %d in 100 batches", app.Completed(), len(builder.batches), total)
+	}
+
+	if !takePreview(t, app, path) {
+		t.Error("no preview saved during the render")
+	}
+}
@@ -0,0 +1,292 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"time"
+
+	"FractalFlame/configuration"
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/generator"
+	"FractalFlame/internal/domain/genome"
+)
+
+// defaultCheckpointInterval - период сохранения контрольных точек, если checkpointInterval не задан.
+const defaultCheckpointInterval = time.Minute
+
+// checkpointState - контрольная точка, загруженная для продолжения рендера.
+type checkpointState struct {
+	histogram *domain.ImageMatrix
+	header    domain.HistogramHeader
+	progress  domain.Progress
+}
+
+// unlimitedStartingPoints - число стартовых точек, если оно не задано, а рендер ограничен временем или
+// плотностью.
+const unlimitedStartingPoints = math.MaxInt32
+
+// setBudget - задает ограничения рендера по времени и по плотности. С ними startingPoints становится верхней
+// границей и может быть не задано.
+func (a *Application) setBudget(config *configuration.Configuration) {
+	a.timeBudget = time.Duration(config.Application.TimeBudget * float64(time.Second))
+	a.targetSamples = config.Application.SamplesPerPixel
+	a.noiseThreshold = config.Application.NoiseThreshold
+	a.previewPath = config.Application.Preview
+	a.previewInterval = time.Duration(config.Application.PreviewInterval * float64(time.Second))
+
+	if a.imageMatrix.StartingPoints == 0 {
+		a.imageMatrix.StartingPoints = unlimitedStartingPoints
+	}
+}
+
+// budgeted - рендер ограничен временем, плотностью или шумом, а не только числом стартовых точек.
+func (a *Application) budgeted() bool {
+	return a.timeBudget > 0 || a.targetSamples > 0 || a.noiseThreshold > 0
+}
+
+// render - обрабатывает стартовые точки с номера from до конца, до исчерпания timeBudget, до достижения
+// плотности targetSamples или шума ниже noiseThreshold или до отмены ctx, число готовых точек сохраняется
+// в completed. Точки обрабатываются порциями, если задан файл контрольной точки или цель по качеству: после
+// порции все точки до ее границы готовы. Раз в checkpointInterval, а также при остановке раньше последней
+// точки состояние сохраняется на диск, так что рендер можно продолжить.
+func (a *Application) render(ctx context.Context, from int) error {
+	total := a.imageMatrix.StartingPoints
+	a.completed = from
+	a.renderStart = time.Now()
+	a.previews.last = a.renderStart
+
+	renderCtx := ctx
+
+	if a.timeBudget > 0 {
+		var cancel context.CancelFunc
+
+		renderCtx, cancel = context.WithTimeout(ctx, a.timeBudget)
+		defer cancel()
+	}
+
+	batch := a.renderBatch(total, from)
+	lastCheckpoint := time.Now()
+	reached := a.goalReached()
+
+	a.progress.Start(total, a.imageMatrix.Iterations, from)
+
+	for a.completed < total && renderCtx.Err() == nil && !reached {
+		to := min(a.completed+batch, total)
+		a.completed += a.fractalBuilder.RenderRange(renderCtx, a.imageMatrix, a.completed, to)
+		reached = a.goalReached()
+
+		if a.previewPath != "" && a.completed < total && !reached && renderCtx.Err() == nil {
+			a.preview()
+		}
+
+		if a.checkpointPath == "" || a.completed == total {
+			continue
+		}
+
+		if renderCtx.Err() != nil || reached || time.Since(lastCheckpoint) >= a.checkpointInterval {
+			if err := a.saveCheckpoint(a.completed); err != nil {
+				a.outputHandler.Write("Error occurred saving checkpoint")
+
+				return err
+			}
+
+			lastCheckpoint = time.Now()
+		}
+	}
+
+	a.renderTime = time.Since(a.renderStart)
+	a.previews.wg.Wait()
+	a.interrupted = ctx.Err() != nil && a.completed < total
+
+	a.outputHandler.FinishProgress()
+
+	switch {
+	case a.interrupted:
+		a.outputHandler.Write("Render interrupted after", a.completed, "starting points")
+	case a.budgeted():
+		a.outputHandler.Write("Render stopped after", a.completed, "starting points,", a.quality())
+	}
+
+	return nil
+}
+
+// noiseGrowth - во сколько раз должно вырасти число итераций с прошлого снимка, чтобы заново оценить шум.
+// Оценка по разнице с одной маленькой порцией слишком случайна и может остановить рендер раньше времени.
+const noiseGrowth = 1.25
+
+// goalReached - достигнута ли цель по качеству: плотность targetSamples или шум ниже noiseThreshold. Шум
+// оценивается по разнице снимка гистограммы с предыдущим, когда число итераций вырастает в noiseGrowth раз.
+// Без цели по качеству всегда false.
+func (a *Application) goalReached() bool {
+	if a.targetSamples > 0 && a.imageMatrix.SamplesPerPixel() >= a.targetSamples {
+		return true
+	}
+
+	samples := a.completed * a.imageMatrix.Iterations
+	if a.noiseThreshold <= 0 || a.snapshot != nil && float64(samples) < noiseGrowth*float64(a.snapshot.Samples) {
+		return false
+	}
+
+	snapshot := a.imageMatrix.Snapshot(samples)
+	a.noise = snapshot.RelativeNoise(a.snapshot, a.gamma())
+	a.snapshot = snapshot
+
+	return a.noise <= a.noiseThreshold
+}
+
+// quality - достигнутое качество рендера: плотность и, если рендер останавливается по шуму, последняя оценка
+// шума.
+func (a *Application) quality() string {
+	quality := fmt.Sprintf("%.1f samples per pixel", a.imageMatrix.SamplesPerPixel())
+	if a.noiseThreshold > 0 && !math.IsInf(a.noise, 1) {
+		quality += fmt.Sprintf(", noise %.4f", a.noise)
+	}
+
+	return quality
+}
+
+// renderBatch - размер порции стартовых точек. При цели по качеству или без ограничения числа точек берется
+// наименьшая порция, которая занимает все ядра процессора, чтобы не уйти далеко за цель. С контрольными точками
+// или превью порция - около процента от числа точек, но не меньше наименьшей. Иначе все точки обрабатываются одной
+// порцией, а время ограничивается отменой контекста. На границе порции рабочие потоки дожидаются друг друга,
+// поэтому порции меньше необходимого не берутся.
+func (a *Application) renderBatch(total, from int) int {
+	minBatch := 4 * runtime.NumCPU()
+
+	switch {
+	case a.targetSamples > 0 || a.noiseThreshold > 0 || total == unlimitedStartingPoints:
+		return minBatch
+	case a.checkpointPath != "" || a.previewPath != "":
+		return max(minBatch, (total+99)/100)
+	default:
+		return total - from
+	}
+}
+
+// progressInterval - период обновления строки состояния рендера.
+const progressInterval = 200 * time.Millisecond
+
+// showProgress - выводит строку состояния рендера. Вызывается из горутины счетчика хода рендера раз
+// в progressInterval и в конце каждого прохода генератора, поэтому рабочие потоки вывода не ждут.
+func (a *Application) showProgress(report generator.ProgressReport) {
+	line := fmt.Sprintf("Rendering: %d/%d starting points (%.0f%%)", report.Completed, report.Total,
+		100*float64(report.Completed)/float64(max(report.Total, 1)))
+	if report.Total == unlimitedStartingPoints {
+		line = fmt.Sprintf("Rendering: %d starting points", report.Completed)
+	}
+
+	if report.IterationsPerSecond > 0 {
+		line += fmt.Sprintf(", %s it/s", formatRate(report.IterationsPerSecond))
+	}
+
+	eta := report.ETA
+	if a.timeBudget > 0 {
+		eta = max(a.timeBudget-time.Since(a.renderStart), 0)
+		if report.Total != unlimitedStartingPoints && report.IterationsPerSecond > 0 {
+			eta = min(eta, report.ETA)
+		}
+	}
+
+	if eta > 0 || report.IterationsPerSecond > 0 && report.Total != unlimitedStartingPoints {
+		line += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+
+	a.outputHandler.Progress(line)
+}
+
+// formatRate - скорость с десятичной приставкой: 12.3M вместо 12300000.
+func formatRate(rate float64) string {
+	for _, prefix := range []string{"", "k", "M"} {
+		if rate < 1000 {
+			return fmt.Sprintf("%.1f%s", rate, prefix)
+		}
+
+		rate /= 1000
+	}
+
+	return fmt.Sprintf("%.1fG", rate)
+}
+
+// saveCheckpoint - записывает контрольную точку во временный файл и переименовывает его, чтобы прерванная
+// запись не испортила предыдущую контрольную точку.
+func (a *Application) saveCheckpoint(completed int) error {
+	tmp := a.checkpointPath + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(file)
+
+	err = a.imageMatrix.WriteCheckpoint(out, completed, genome.FromMatrix(a.imageMatrix).Hash())
+	if err == nil {
+		err = out.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, a.checkpointPath)
+}
+
+// loadCheckpoint - читает контрольную точку для продолжения рендера, пустой путь означает рендер с начала.
+// Seed берется из контрольной точки, чтобы случайный геном и генераторы стартовых точек совпали с прерванным
+// рендером, а новые контрольные точки по умолчанию пишутся в тот же файл.
+func (a *Application) loadCheckpoint(path string) (*checkpointState, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if a.memoryLimit > 0 {
+		return nil, errors.ErrTiledMode{Reason: "resuming from a checkpoint is not supported"}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	histogram, header, progress, err := domain.ReadCheckpoint(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+
+	a.imageMatrix.Seed = header.Seed
+
+	if a.checkpointPath == "" {
+		a.checkpointPath = path
+	}
+
+	return &checkpointState{histogram: histogram, header: header, progress: progress}, nil
+}
+
+// resume - переносит гистограмму контрольной точки в подготовленный буфер и возвращает номер стартовой точки,
+// с которой продолжается рендер. Без контрольной точки рендер начинается с нулевой точки.
+func (a *Application) resume(state *checkpointState) (int, error) {
+	if state == nil {
+		return 0, nil
+	}
+
+	a.imageMatrix.SetCamera(state.header.Camera)
+
+	from, err := a.imageMatrix.Resume(state.histogram, state.header, state.progress, genome.FromMatrix(a.imageMatrix).Hash())
+	if err != nil {
+		return 0, err
+	}
+
+	a.outputHandler.Write("Resuming from starting point", from, "of", a.imageMatrix.StartingPoints)
+
+	return from, nil
+}
@@ -0,0 +1,250 @@
+package application_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"FractalFlame/configuration"
+	"FractalFlame/internal/application"
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/transformations"
+)
+
+// countingBuilder - генератор, который кладет по одному попаданию на стартовую точку в пиксель (0, 0)
+// и тратит на точку delay. После отмены контекста точки не обрабатываются, как у настоящих генераторов.
+type countingBuilder struct {
+	delay time.Duration
+}
+
+func (b countingBuilder) RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int {
+	done := 0
+
+	for i := from; i < to && ctx.Err() == nil; i++ {
+		time.Sleep(b.delay)
+
+		im.Pixels[0][0].HitRate++
+		done++
+	}
+
+	return done
+}
+
+var _ application.FractalBuilder = countingBuilder{}
+
+// budgetConfig - конфигурация с числом стартовых точек и ограничениями по времени в секундах и по плотности.
+func budgetConfig(startingPoints int, timeBudget, samplesPerPixel float64) *configuration.Configuration {
+	config := &configuration.Configuration{}
+	config.Application.StartingPoints = startingPoints
+	config.Application.TimeBudget = timeBudget
+	config.Application.SamplesPerPixel = samplesPerPixel
+
+	return config
+}
+
+// checkQuality - метаданные сообщают достигнутую плотность и число стартовых точек, а рендер не помечен
+// прерванным.
+func checkQuality(t *testing.T, app *application.Application, im *domain.ImageMatrix) {
+	t.Helper()
+
+	meta := app.Metadata()
+	want := fmt.Sprintf("%.1f samples per pixel, %d starting points, %d samples",
+		im.SamplesPerPixel(), app.Completed(), app.Completed()*im.Iterations)
+
+	if !strings.HasPrefix(meta["Quality"], want) {
+		t.Errorf("quality metadata %q, want prefix %q", meta["Quality"], want)
+	}
+
+	if partial, ok := meta["Partial"]; ok {
+		t.Errorf("render stopped by its budget is marked as interrupted: %q", partial)
+	}
+}
+
+func TestRender_StopsOnTimeBudget(t *testing.T) {
+	const budget = 50 * time.Millisecond
+
+	im := domain.NewImageMatrix(2, 2, 0, 10)
+	app := application.NewRenderApp(im, countingBuilder{delay: 100 * time.Microsecond}, budgetConfig(0, budget.Seconds(), 0))
+
+	start := time.Now()
+	if err := app.Render(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	elapsed := time.Since(start)
+	if elapsed < budget || elapsed > time.Second {
+		t.Errorf("render took %v with a budget of %v", elapsed, budget)
+	}
+
+	if app.Completed() == 0 || app.Completed() != im.Pixels[0][0].HitRate {
+		t.Errorf("%d starting points reported, %d rendered", app.Completed(), im.Pixels[0][0].HitRate)
+	}
+
+	checkQuality(t, app, im)
+}
+
+func TestRender_StopsOnSamplesPerPixelTarget(t *testing.T) {
+	const target = 10
+
+	im := domain.NewImageMatrix(2, 2, 0, 10)
+	app := application.NewRenderApp(im, countingBuilder{}, budgetConfig(0, 0, target))
+
+	if err := app.Render(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Плотность target на четыре пикселя - это 4 * target стартовых точек. Рендер останавливается на первой
+	// порции, после которой цель достигнута.
+	batch := 4 * runtime.NumCPU()
+	if want := (4*target + batch - 1) / batch * batch; app.Completed() != want {
+		t.Errorf("render stopped after %d starting points, want %d", app.Completed(), want)
+	}
+
+	checkQuality(t, app, im)
+}
+
+func TestRender_StartingPointsBoundBudgetedRender(t *testing.T) {
+	im := domain.NewImageMatrix(2, 2, 20, 10)
+	app := application.NewRenderApp(im, countingBuilder{}, budgetConfig(20, 60, 1000))
+
+	if err := app.Render(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if app.Completed() != 20 {
+		t.Errorf("render stopped after %d starting points, want all 20 before the unreachable target", app.Completed())
+	}
+
+	checkQuality(t, app, im)
+}
+
+// recordingOutput - вывод приложения, который запоминает сообщения.
+type recordingOutput struct {
+	lines []string
+}
+
+func (o *recordingOutput) Write(messages ...interface{}) {
+	o.lines = append(o.lines, strings.TrimSuffix(fmt.Sprintln(messages...), "\n"))
+}
+
+func (o *recordingOutput) Progress(string) {}
+
+func (o *recordingOutput) FinishProgress() {}
+
+// stoppingBuilder - генератор, который отменяет рендер перед стартовой точкой номер at.
+type stoppingBuilder struct {
+	countingBuilder
+
+	cancel context.CancelFunc
+	at     int
+}
+
+func (b stoppingBuilder) RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int {
+	if from <= b.at && b.at < to {
+		done := b.countingBuilder.RenderRange(ctx, im, from, b.at)
+		b.cancel()
+
+		return done
+	}
+
+	return b.countingBuilder.RenderRange(ctx, im, from, to)
+}
+
+func TestRenderImage_InterruptSavesPartialImage(t *testing.T) {
+	inTempDir(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	im := domain.NewImageMatrix(4, 4, 100, 10)
+	im.BitDepth = 8
+
+	out := &recordingOutput{}
+	app := application.NewRenderApp(im, stoppingBuilder{cancel: cancel, at: 40}, budgetConfig(100, 0, 0))
+	app.SetOutput(out)
+
+	if err := app.RenderImage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "render interrupted after 40 of 100 starting points, 400 samples"
+	if partial := pngText(t, "FractalFlame.png")["Partial"]; partial != want {
+		t.Errorf("partial metadata %q, want %q", partial, want)
+	}
+
+	if !slices.Contains(out.lines, "Render interrupted after 40 starting points") {
+		t.Errorf("output %q does not report the interruption", out.lines)
+	}
+}
+
+// startCancelled - запускает новое приложение по конфигурации config в текущем каталоге с уже отмененным
+// контекстом и возвращает его вывод.
+func startCancelled(t *testing.T, config string) (*recordingOutput, error) {
+	t.Helper()
+
+	if err := os.WriteFile("config.json", []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out := &recordingOutput{}
+	app := application.NewApp(slog.New(slog.NewTextHandler(io.Discard, nil)), out)
+	source := "config.json"
+
+	return out, app.Start(ctx, &source, "")
+}
+
+func TestStart_WritesToInjectedOutput(t *testing.T) {
+	inTempDir(t)
+
+	out, err := startCancelled(t, `{"Application": {"width": 8, "height": 8, "startingPoints": 10, "iterations": 10,
+		"seed": 1, "singleThread": true, "format": "PNG"}, "LinearTransformations": {"Linear": true}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Contains(out.lines, "Seed: 1") {
+		t.Errorf("output %q does not contain the seed, setUp replaced the injected output", out.lines)
+	}
+}
+
+func TestStart_RegistersCustomTransformationsPerRun(t *testing.T) {
+	inTempDir(t)
+
+	config := `{"Application": {"width": 8, "height": 8, "startingPoints": 10, "iterations": 10, "seed": 1,
+		"singleThread": true, "format": "PNG"}, "LinearTransformations": {"Linear": true},
+		"CustomTransformations": [{"name": "Shrink", "code": ["x' = x / 2", "y' = y / 2"]}]}`
+
+	for run := 1; run <= 2; run++ {
+		if _, err := startCancelled(t, config); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	if _, ok := transformations.Lookup("Shrink"); ok {
+		t.Error("custom transformation stays registered after Start returns")
+	}
+}
+
+func TestStart_RejectsBlankCustomTransformationName(t *testing.T) {
+	inTempDir(t)
+
+	_, err := startCancelled(t, `{"Application": {"width": 8, "height": 8, "startingPoints": 10, "iterations": 10,
+		"format": "PNG"}, "LinearTransformations": {"Linear": true},
+		"CustomTransformations": [{"name": " ", "code": ["x' = x", "y' = y"]}]}`)
+
+	want := domainErrors.ErrInvalidTransformationName{Name: " "}.Error()
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Start with a blank transformation name = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,262 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/savers"
+)
+
+// megabyte - единица memoryLimit в конфигурации.
+const megabyte = 1 << 20
+
+// subImager - изображения стандартной библиотеки, из которых можно вырезать собственные строки полосы.
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// bandLayout - разбиение изображения на полосы тайлового рендера: rows строк изображения на полосу и margin
+// строк соседних полос сверху и снизу для фильтра.
+type bandLayout struct {
+	rows   int
+	margin int
+	count  int
+}
+
+// checkTiled - проверяет, что конфигурация совместима с тайловым рендером. Полосы рендерятся независимо
+// и сразу записываются в PNG, поэтому преобразования всего изображения и продолжение рендера недоступны.
+func (a *Application) checkTiled() error {
+	_, png := a.saver.(*savers.PngSaver)
+
+	switch {
+	case a.imageMatrix.Projection() != domain.ProjectionPlane:
+		return errors.ErrTiledMode{Reason: "projections are not supported"}
+	case a.imageMatrix.Stereo() != nil:
+		return errors.ErrTiledMode{Reason: "stereo pairs are not supported"}
+	case a.imageMatrix.Tileable:
+		return errors.ErrTiledMode{Reason: "tileable images are not supported"}
+	case a.symmetry.xSymmetry || a.symmetry.ySymmetry:
+		return errors.ErrTiledMode{Reason: "mirror symmetry is not supported"}
+	case !png:
+		return errors.ErrTiledMode{Reason: "only PNG output is supported"}
+	case a.checkpointPath != "" || a.previewPath != "" || a.histogramOutput != "" || a.budgeted():
+		return errors.ErrTiledMode{Reason: "checkpoints, previews, histogram output and render budgets are not supported"}
+	}
+
+	return nil
+}
+
+// layoutBands - выбирает высоту полосы так, чтобы буфер полосы вместе со строками для фильтра занимал не
+// больше memoryLimit.
+func (a *Application) layoutBands() (bandLayout, error) {
+	width, height := a.imageMatrix.Resolution.Width, a.imageMatrix.Resolution.Height
+	margin := domain.BandMargin(a.filterRadius)
+
+	rows := int(a.memoryLimit/(int64(width)*domain.TileBytesPerPixel)) - 2*margin
+	if rows < 1 {
+		return bandLayout{}, errors.ErrTiledMode{Reason: "memory limit is too small for a single band"}
+	}
+
+	rows = min(rows, height)
+
+	return bandLayout{rows: rows, margin: margin, count: (height + rows - 1) / rows}, nil
+}
+
+// bandBuffer - буфер, в который помещается любая полоса layout вместе со строками для фильтра. Один буфер
+// используется для всех полос по очереди.
+func (a *Application) bandBuffer(layout bandLayout) *domain.ImageMatrix {
+	return a.imageMatrix.Band(0, min(layout.rows+2*layout.margin, a.imageMatrix.Resolution.Height))
+}
+
+// band - переносит буфер на полосу i вместе со строками для фильтра и возвращает номер ее первой собственной
+// строки в буфере.
+func (a *Application) band(buffer *domain.ImageMatrix, layout bandLayout, i int) (skip int) {
+	height := a.imageMatrix.Resolution.Height
+	top := i * layout.rows
+	from, to := max(top-layout.margin, 0), min(top+layout.rows+layout.margin, height)
+
+	buffer.MoveBand(from, to-from)
+
+	return top - from
+}
+
+// renderTiled - рендер изображения, которое не помещается в память, по горизонтальным полосам. Сначала для
+// каждой полосы заново проигрываются все стартовые точки, и ее гистограмма сбрасывается во временный файл,
+// заодно находится наибольшее число попаданий во всем изображении. Затем полосы по очереди читаются,
+// проходят тональную компрессию с этой общей нормировкой и фильтр, и их строки сразу сжимаются в PNG.
+// В памяти находится один буфер полосы, который переносится с полосы на полосу. При отмене ctx сохраняются
+// готовые полосы, а строки остальных остаются пустыми.
+func (a *Application) renderTiled(ctx context.Context) error {
+	layout, err := a.layoutBands()
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "fractalflame-bands-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	a.outputHandler.Write("Tiled render:", layout.count, "bands of", layout.rows, "rows")
+
+	band := a.bandBuffer(layout)
+
+	finished, maxHits, err := a.renderBands(ctx, band, layout, dir)
+	if err != nil {
+		return err
+	}
+
+	a.outputHandler.FinishProgress()
+
+	a.completed = a.imageMatrix.StartingPoints
+	a.interrupted = finished < layout.count
+
+	if a.interrupted {
+		a.outputHandler.Write("Render interrupted after", finished, "of", layout.count, "bands, the remaining rows are empty")
+	}
+
+	if err := a.developTiled(band, layout, dir, finished, maxHits); err != nil {
+		a.outputHandler.Write("Error occurred saving image restart please")
+
+		return errors.ErrSavingImage{Err: err}
+	}
+
+	a.outputHandler.Write("Изображение сохранено как", outputName)
+
+	return nil
+}
+
+// renderBands - рендер полос по очереди в буфер band со сбросом их гистограмм во временные файлы в dir.
+// Возвращает число готовых полос (меньше layout.count, если ctx отменен) и наибольшее число попаданий в них.
+func (a *Application) renderBands(ctx context.Context, band *domain.ImageMatrix, layout bandLayout,
+	dir string) (finished, maxHits int, err error) {
+	total := a.imageMatrix.StartingPoints
+	a.progress.Start(layout.count*total, a.imageMatrix.Iterations, 0)
+
+	for finished < layout.count {
+		a.band(band, layout, finished)
+
+		if done := a.fractalBuilder.RenderRange(ctx, band, 0, total); done < total {
+			return finished, maxHits, nil
+		}
+
+		maxHits = max(maxHits, band.MaxHits())
+
+		if err := writeBand(bandPath(dir, finished), band); err != nil {
+			return finished, maxHits, err
+		}
+
+		finished++
+	}
+
+	return finished, maxHits, nil
+}
+
+// developTiled - тональная компрессия и фильтр первых finished полос из временных файлов в буфере band
+// и потоковая запись их в PNG. Строки остальных полос получают только фон. Изображение результата тоже
+// выделяется один раз на все полосы.
+func (a *Application) developTiled(band *domain.ImageMatrix, layout bandLayout, dir string, finished, maxHits int) error {
+	meta := a.metadata()
+	meta["Tiled"] = fmt.Sprintf("%d bands of %d rows", layout.count, layout.rows)
+
+	if finished < layout.count {
+		meta["Partial"] = fmt.Sprintf("tiled render interrupted after %d of %d bands, rows from %d are empty",
+			finished, layout.count, finished*layout.rows)
+	}
+
+	file, err := os.Create(outputName + ".png")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+	width, height := a.imageMatrix.Resolution.Width, a.imageMatrix.Resolution.Height
+
+	stream, err := savers.NewPngStream(out, width, height, a.imageMatrix.BitDepth, meta)
+	if err != nil {
+		return err
+	}
+
+	var img image.Image
+
+	for i := 0; i < layout.count; i++ {
+		skip := a.band(band, layout, i)
+
+		if i < finished {
+			if err := readBand(bandPath(dir, i), band); err != nil {
+				return err
+			}
+		}
+
+		band.ToneMapMax(a.gamma(), maxHits)
+		band.Filter(a.filterRadius)
+
+		rows := min(layout.rows, height-i*layout.rows)
+		img = band.ConvertToImageInto(img)
+
+		if err := stream.WriteRows(img.(subImager).SubImage(image.Rect(0, skip, width, skip+rows))); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		return err
+	}
+
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func bandPath(dir string, i int) string {
+	return filepath.Join(dir, "band"+strconv.Itoa(i)+".hist")
+}
+
+// writeBand - сбрасывает гистограмму полосы во временный файл.
+func writeBand(path string, band *domain.ImageMatrix) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	out := bufio.NewWriter(file)
+
+	if err := band.WriteHistogram(out, 0); err != nil {
+		return err
+	}
+
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// readBand - читает гистограмму полосы из временного файла и удаляет его.
+func readBand(path string, band *domain.ImageMatrix) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = band.LoadHistogram(bufio.NewReader(file))
+	file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
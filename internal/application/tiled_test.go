@@ -0,0 +1,207 @@
+package application_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/png"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"FractalFlame/internal/application"
+	"FractalFlame/internal/domain"
+)
+
+// inTempDir - выполняет тест в пустом временном каталоге, куда сохраняется результат.
+func inTempDir(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// samplePeakHeap - раз в миллисекунду замеряет HeapInuse, пока не вызвана возвращаемая функция, которая
+// возвращает наибольший замер.
+func samplePeakHeap() (stop func() uint64) {
+	done := make(chan struct{})
+	peak := make(chan uint64)
+
+	go func() {
+		var (
+			stats runtime.MemStats
+			most  uint64
+		)
+
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			runtime.ReadMemStats(&stats)
+			most = max(most, stats.HeapInuse)
+
+			select {
+			case <-done:
+				peak <- most
+
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() uint64 {
+		close(done)
+
+		return <-peak
+	}
+}
+
+func TestRenderTiled_PeakHeapStaysWithinMemoryLimit(t *testing.T) {
+	const (
+		width       = 1000
+		height      = 2000
+		memoryLimit = 16 << 20
+		// headroom - запас на сборщик мусора, буферы сжатия и замеры.
+		headroom = memoryLimit / 2
+	)
+
+	inTempDir(t)
+
+	im := domain.NewTiledImageMatrix(width, height, 8, 1)
+	im.BitDepth = 8
+
+	// Полоса около 100 строк, то есть изображение - это 20 полос: если бы буферы полос, фильтра и результата
+	// выделялись для каждой полосы заново, куча выросла бы далеко за memoryLimit.
+	app := application.NewTiledApp(im, countingBuilder{}, memoryLimit, 1.5)
+
+	var before runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	stop := samplePeakHeap()
+	err := app.RenderTiled(context.Background())
+	peak := stop()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if used := peak - min(peak, before.HeapInuse); used > memoryLimit+headroom {
+		t.Errorf("tiled render used up to %d MiB of heap with a limit of %d MiB", used>>20, memoryLimit>>20)
+	}
+
+	file, err := os.Open("FractalFlame.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	config, err := png.DecodeConfig(file)
+	if err != nil || config.Width != width || config.Height != height {
+		t.Errorf("saved image is %dx%d (%v), want %dx%d", config.Width, config.Height, err, width, height)
+	}
+}
+
+// pngText - текстовые чанки tEXt файла PNG.
+func pngText(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := map[string]string{}
+
+	for pos := len("\x89PNG\r\n\x1a\n"); pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		kind := string(data[pos+4 : pos+8])
+		body := data[pos+8 : pos+8+length]
+
+		if kind == "tEXt" {
+			if key, value, ok := bytes.Cut(body, []byte{0}); ok {
+				text[string(key)] = string(value)
+			}
+		}
+
+		pos += 12 + length
+	}
+
+	return text
+}
+
+// cancellingBuilder - генератор, который отменяет рендер, начиная с вызова RenderRange номер after + 1.
+type cancellingBuilder struct {
+	countingBuilder
+
+	cancel context.CancelFunc
+	after  int
+	calls  int
+}
+
+func (b *cancellingBuilder) RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int {
+	b.calls++
+	if b.calls > b.after {
+		b.cancel()
+	}
+
+	return b.countingBuilder.RenderRange(ctx, im, from, to)
+}
+
+func TestRenderTiled_InterruptSavesFinishedBands(t *testing.T) {
+	inTempDir(t)
+
+	im := domain.NewTiledImageMatrix(40, 30, 8, 1)
+	im.BitDepth = 8
+	im.Background = &domain.Background{Transparent: true}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Полосы по 5 строк без строк для фильтра, рендер отменяется на третьей полосе.
+	app := application.NewTiledApp(im, &cancellingBuilder{cancel: cancel, after: 2}, 40*domain.TileBytesPerPixel*5, 0)
+
+	if err := app.RenderTiled(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "tiled render interrupted after 2 of 6 bands, rows from 10 are empty"
+	if partial := pngText(t, "FractalFlame.png")["Partial"]; partial != want {
+		t.Errorf("partial metadata %q, want %q", partial, want)
+	}
+
+	file, err := os.Open("FractalFlame.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Генератор кладет попадания в первую строку каждой полосы.
+	if _, _, _, alpha := img.At(0, 5).RGBA(); alpha == 0 {
+		t.Error("finished band 1 is empty")
+	}
+
+	if _, _, _, alpha := img.At(0, 10).RGBA(); alpha != 0 {
+		t.Error("interrupted band 2 is not empty")
+	}
+}
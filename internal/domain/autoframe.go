@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"FractalFlame/pkg/random"
+)
+
+// Параметры предварительного прохода автоматического кадрирования.
+const (
+	autoFrameStartingPoints = 16
+	autoFrameIterations     = 5000
+	autoFrameLowPercentile  = 0.01
+	autoFrameHighPercentile = 0.99
+	autoFrameMargin         = 0.05
+	// autoFrameMinExtent - меньший разброс координат считается вырожденным: аттрактор сжался в точку или линию.
+	autoFrameMinExtent = 1e-6
+	// autoFrameStream - номера стартовых точек предварительного прохода, чтобы он не совпадал с основным рендером.
+	autoFrameStream = 1 << 62
+)
+
+// AutoFrame - выполняет короткий предварительный проход, собирает точки аттрактора и подбирает камеру так,
+// чтобы в кадр попала область между 1-м и 99-м перцентилями координат. Поворот текущей камеры сохраняется.
+// Если собрать точки не удалось или аттрактор сжался в точку, камера не меняется. Если он сжался в линию,
+// масштаб подбирается по ее длине.
+func (im *ImageMatrix) AutoFrame() Camera {
+	cam := im.camera
+	rotate := cam.Rotate * math.Pi / 180
+	cos, sin := math.Cos(rotate), math.Sin(rotate)
+
+	us := make([]float64, 0, autoFrameStartingPoints*autoFrameIterations)
+	vs := make([]float64, 0, autoFrameStartingPoints*autoFrameIterations)
+
+	collect := func(rng *rand.Rand, x, y, z float64, _ *AffineTransformation) {
+		x, y, ok := im.project(rng, x, y, z)
+		if !ok || math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+			return
+		}
+
+		us = append(us, x*cos+y*sin)
+		vs = append(vs, y*cos-x*sin)
+	}
+
+	workerRand := random.NewWorkerRand()
+
+	for i := 0; i < autoFrameStartingPoints; i++ {
+		im.iterate(autoFrameStream+i, autoFrameIterations, workerRand, collect)
+	}
+
+	if len(us) == 0 {
+		return cam
+	}
+
+	uMin, uMax := percentileRange(us)
+	vMin, vMax := percentileRange(vs)
+
+	width, height := uMax-uMin, vMax-vMin
+	if width < autoFrameMinExtent && height < autoFrameMinExtent {
+		return cam
+	}
+
+	frameWidth, frameHeight := im.frame()
+
+	// Вырожденная сторона не ограничивает масштаб.
+	scale := math.Inf(1)
+	if width >= autoFrameMinExtent {
+		scale = float64(frameWidth) / width
+	}
+
+	if height >= autoFrameMinExtent {
+		scale = math.Min(scale, float64(frameHeight)/height)
+	}
+
+	uCenter, vCenter := (uMin+uMax)/2, (vMin+vMax)/2
+
+	cam.CenterX = uCenter*cos - vCenter*sin
+	cam.CenterY = uCenter*sin + vCenter*cos
+	cam.Scale = scale * (1 - 2*autoFrameMargin)
+	cam.Zoom = 0
+
+	im.SetCamera(cam)
+
+	return cam
+}
+
+// percentileRange - возвращает 1-й и 99-й перцентили значений, срез сортируется на месте.
+func percentileRange(values []float64) (low, high float64) {
+	slices.Sort(values)
+
+	last := float64(len(values) - 1)
+
+	return values[int(last*autoFrameLowPercentile)], values[int(last*autoFrameHighPercentile)]
+}
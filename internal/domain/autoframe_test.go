@@ -0,0 +1,47 @@
+package domain_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/transformations"
+)
+
+func TestAutoFrame_KeepsCameraForPointAttractor(t *testing.T) {
+	im, tr := constantFlame(0.3, 0.1)
+	tr.Variations = []domain.Variation{{Name: "Linear", Weight: 1, Func: transformations.Linear}}
+
+	camera := domain.Camera{CenterX: 0.1, Scale: 80, Zoom: 0.5, Rotate: 10}
+	im.SetCamera(camera)
+
+	if got := im.AutoFrame(); got != camera || im.Camera() != camera {
+		t.Errorf("camera changed to %+v for an attractor collapsed to a point, want %+v", im.Camera(), camera)
+	}
+}
+
+func TestAutoFrame_FramesLineAttractorByItsLength(t *testing.T) {
+	im, tr := constantFlame(0, 0)
+	tr.Variations = []domain.Variation{{Name: "segment", Weight: 1, Func: func(rng *rand.Rand, _, _ float64) (newX, newY float64) {
+		return 0.2 + rng.Float64(), 0.3
+	}}}
+
+	cam := im.AutoFrame()
+
+	// Отрезок от 0.2 до 1.2 между перцентилями занимает 0.98, с полями 5% с каждой стороны.
+	wantScale := 200 / 0.98 * 0.9
+	if math.IsInf(cam.Scale, 0) || math.Abs(cam.Scale-wantScale) > 0.05*wantScale {
+		t.Errorf("scale %v, want about %v", cam.Scale, wantScale)
+	}
+
+	if math.Abs(cam.CenterX-0.7) > 0.02 || math.Abs(cam.CenterY-0.3) > 1e-9 {
+		t.Errorf("centre (%v, %v), want about (0.7, 0.3)", cam.CenterX, cam.CenterY)
+	}
+
+	renderPoints(im, 0, 1)
+
+	if len(hitPixels(im)) == 0 {
+		t.Error("nothing was plotted after auto framing a line")
+	}
+}
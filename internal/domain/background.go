@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// Background - фон, на который накладывается пламя: прозрачный, сплошной цвет или вертикальный градиент
+// от цвета Top вверху кадра к цвету Bottom внизу.
+type Background struct {
+	Transparent bool
+	Top         color.RGBA
+	Bottom      color.RGBA
+}
+
+// ParseBackground - разбирает фон из конфигурации: "transparent", цвет "#rrggbb" или градиент
+// "#rrggbb,#rrggbb". Пустая строка означает черный фон без альфа-канала, как и раньше.
+func ParseBackground(spec string) (*Background, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "":
+		return nil, nil
+	case "transparent":
+		return &Background{Transparent: true}, nil
+	}
+
+	colours := strings.Split(spec, ",")
+	if len(colours) > 2 {
+		return nil, errors.ErrBackground{Spec: spec}
+	}
+
+	top, err := parseHexColour(colours[0])
+	if err != nil {
+		return nil, errors.ErrBackground{Spec: spec}
+	}
+
+	bottom := top
+
+	if len(colours) == 2 {
+		if bottom, err = parseHexColour(colours[1]); err != nil {
+			return nil, errors.ErrBackground{Spec: spec}
+		}
+	}
+
+	return &Background{Top: top, Bottom: bottom}, nil
+}
+
+func parseHexColour(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) != 6 {
+		return color.RGBA{}, strconv.ErrSyntax
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// colourAt - цвет фона в строке y кадра высотой height.
+func (b *Background) colourAt(y, height int) color.RGBA {
+	if height < 2 {
+		return b.Top
+	}
+
+	t := float64(y) / float64(height-1)
+	mix := func(top, bottom uint8) uint8 {
+		return uint8(math.Round(float64(top) + (float64(bottom)-float64(top))*t))
+	}
+
+	return color.RGBA{R: mix(b.Top.R, b.Bottom.R), G: mix(b.Top.G, b.Bottom.G), B: mix(b.Top.B, b.Bottom.B), A: 255}
+}
+
+// outputColour - переводит цвет пикселя (с альфа-каналом, умноженным на цвет) в цвет результата без умножения
+// на альфу. Без фона пламя выводится на черном, на фоне цвета или градиента - накладывается на него, а на
+// прозрачном фоне сохраняется альфа-канал, цвет при этом либо делится на альфу, либо остается умноженным на нее.
+// На фоне диапазон не ограничивается: целые форматы ограничивают его при квантовании, а линейные данные
+// HDR-форматов могут быть больше 1.
+func (im *ImageMatrix) outputColour(c FloatColour, y int) FloatColour {
+	switch {
+	case im.Background == nil:
+		return FloatColour{c[0], c[1], c[2], 1}
+	case im.Background.Transparent:
+		alpha := c[3]
+		if im.PremultipliedAlpha || alpha == 0 || alpha == 1 {
+			return c
+		}
+
+		return FloatColour{math.Min(1, c[0]/alpha), math.Min(1, c[1]/alpha), math.Min(1, c[2]/alpha), alpha}
+	}
+
+	_, height := im.frame()
+	if im.band != nil {
+		y, height = y+im.band.top, im.band.imageHeight
+	}
+
+	bg := im.Background.colourAt(y%height, height)
+	over := func(v float64, background uint8) float64 {
+		return v + float64(background)/255*(1-c[3])
+	}
+
+	return FloatColour{over(c[0], bg.R), over(c[1], bg.G), over(c[2], bg.B), 1}
+}
@@ -0,0 +1,90 @@
+package domain_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+)
+
+// densityMatrix - матрица 3x3, в которой пиксель (0, 0) получил 1000 попаданий, (1, 0) - 10, остальные пусты.
+func densityMatrix() *domain.ImageMatrix {
+	im := domain.NewImageMatrix(3, 3, 1, 1)
+	xform := &domain.AffineTransformation{TransformationColour: color.RGBA{R: 200, G: 100, B: 50, A: 255}}
+
+	for i := 0; i < 1000; i++ {
+		im.UpdatePixel(0, 0, xform)
+	}
+
+	for i := 0; i < 10; i++ {
+		im.UpdatePixel(0, 1, xform)
+	}
+
+	im.ToneMap(1)
+
+	return im
+}
+
+func TestConvertToImage_TransparentBackgroundKeepsAlpha(t *testing.T) {
+	im := densityMatrix()
+	im.Background = &domain.Background{Transparent: true}
+
+	img := im.ConvertToImage().(*image.NRGBA)
+
+	dense, sparse, empty := img.NRGBAAt(0, 0), img.NRGBAAt(1, 0), img.NRGBAAt(2, 2)
+
+	if dense != (color.NRGBA{R: 200, G: 100, B: 50, A: 255}) {
+		t.Errorf("densest pixel %v, want opaque source colour", dense)
+	}
+
+	if empty.A != 0 {
+		t.Errorf("empty pixel alpha %d, want 0", empty.A)
+	}
+
+	// Плотность 10 из 1000 в логарифмической шкале дает треть яркости, цвет без умножения на альфу сохраняется.
+	if sparse.A < 80 || sparse.A > 90 || sparse.R < 195 || sparse.R > 205 {
+		t.Errorf("sparse pixel %v, want alpha about 85 and straight colour about 200", sparse)
+	}
+
+	im.PremultipliedAlpha = true
+
+	premultiplied := im.ConvertToImage().(*image.NRGBA).NRGBAAt(1, 0)
+	if premultiplied.A != sparse.A || premultiplied.R > 70 {
+		t.Errorf("premultiplied sparse pixel %v, want colour multiplied by alpha %d", premultiplied, sparse.A)
+	}
+}
+
+func TestConvertToImage_CompositesOntoGradient(t *testing.T) {
+	im := densityMatrix()
+
+	background, err := domain.ParseBackground("#000010, #3060ff")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im.Background = background
+	img := im.ConvertToImage()
+
+	if c := color.NRGBAModel.Convert(img.At(2, 0)); c != (color.NRGBA{B: 0x10, A: 255}) {
+		t.Errorf("top of the gradient %v", c)
+	}
+
+	if c := color.NRGBAModel.Convert(img.At(2, 2)); c != (color.NRGBA{R: 0x30, G: 0x60, B: 0xff, A: 255}) {
+		t.Errorf("bottom of the gradient %v", c)
+	}
+
+	if c := color.NRGBAModel.Convert(img.At(0, 0)); c != (color.NRGBA{R: 200, G: 100, B: 50, A: 255}) {
+		t.Errorf("opaque flame pixel %v, background must not show through", c)
+	}
+}
+
+func TestParseBackground_RejectsMalformedColours(t *testing.T) {
+	for _, spec := range []string{"red", "#12345", "#000000,#ffffff,#000000", "#gg0000"} {
+		if _, err := domain.ParseBackground(spec); !errors.As(err, &domainErrors.ErrBackground{}) {
+			t.Errorf("%q: got %v, want ErrBackground", spec, err)
+		}
+	}
+}
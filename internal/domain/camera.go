@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Camera - камера плоскости изображения: центр кадра, масштаб в пикселях на единицу, зум (каждая единица
+// увеличивает изображение вдвое) и поворот в градусах.
+type Camera struct {
+	CenterX float64
+	CenterY float64
+	Scale   float64
+	Zoom    float64
+	Rotate  float64
+}
+
+// view - предвычисленное отображение мировых координат в пиксели для текущей камеры.
+type view struct {
+	centerX       float64
+	centerY       float64
+	pixelsPerUnit float64
+	zoom          float64
+	cos, sin      float64
+	halfWidth     float64
+	halfHeight    float64
+}
+
+// DefaultScale - масштаб, при котором меньшая сторона изображения покрывает отрезок [-1; 1].
+func DefaultScale(width, height int) float64 {
+	return float64(min(width, height)) / 2
+}
+
+func newView(c Camera, width, height int) view {
+	rotate := c.Rotate * math.Pi / 180
+
+	return view{
+		centerX:       c.CenterX,
+		centerY:       c.CenterY,
+		pixelsPerUnit: c.Scale * math.Pow(2, c.Zoom),
+		zoom:          math.Pow(2, c.Zoom),
+		cos:           math.Cos(rotate),
+		sin:           math.Sin(rotate),
+		halfWidth:     float64(width) / 2,
+		halfHeight:    float64(height) / 2,
+	}
+}
+
+// toPixel - переводит точку плоскости в дробные координаты пикселя.
+func (v *view) toPixel(x, y float64) (pixelX, pixelY float64) {
+	dx, dy := x-v.centerX, y-v.centerY
+
+	pixelX = (dx*v.cos+dy*v.sin)*v.pixelsPerUnit + v.halfWidth
+	pixelY = (dy*v.cos-dx*v.sin)*v.pixelsPerUnit + v.halfHeight
+
+	return pixelX, pixelY
+}
+
+// toPlane - переводит точку в координаты плоскости камеры: относительно центра, с учетом поворота и зума,
+// но без масштаба в пикселях.
+func (v *view) toPlane(x, y float64) (u, w float64) {
+	dx, dy := x-v.centerX, y-v.centerY
+
+	return (dx*v.cos + dy*v.sin) * v.zoom, (dy*v.cos - dx*v.sin) * v.zoom
+}
+
+// toWorld - переводит координаты пикселя обратно в точку плоскости.
+func (v *view) toWorld(pixelX, pixelY float64) (x, y float64) {
+	dx := (pixelX - v.halfWidth) / v.pixelsPerUnit
+	dy := (pixelY - v.halfHeight) / v.pixelsPerUnit
+
+	return v.centerX + dx*v.cos - dy*v.sin, v.centerY + dx*v.sin + dy*v.cos
+}
+
+// Camera3D - камера для трехмерного пламени: поворот по тангажу и рысканию, перспектива и смещение по z.
+// DOF задает силу размытия глубины резкости, Focus - расстояние до плоскости фокуса.
+type Camera3D struct {
+	Pitch       float64
+	Yaw         float64
+	Perspective float64
+	ZPos        float64
+	DOF         float64
+	Focus       float64
+	matrix      [3][3]float64
+}
+
+// NewCamera3D - создает камеру, углы pitch и yaw задаются в градусах.
+func NewCamera3D(pitch, yaw, perspective, zPos float64) *Camera3D {
+	c := &Camera3D{Pitch: pitch, Yaw: yaw, Perspective: perspective, ZPos: zPos}
+
+	pitchRad := pitch * math.Pi / 180
+	yawRad := -yaw * math.Pi / 180
+
+	c.matrix = [3][3]float64{
+		{math.Cos(yawRad), -math.Sin(yawRad), 0},
+		{math.Cos(pitchRad) * math.Sin(yawRad), math.Cos(pitchRad) * math.Cos(yawRad), -math.Sin(pitchRad)},
+		{math.Sin(pitchRad) * math.Sin(yawRad), math.Sin(pitchRad) * math.Cos(yawRad), math.Cos(pitchRad)},
+	}
+
+	return c
+}
+
+// Project - проецирует точку пространства на плоскость изображения, ok = false для точек за камерой.
+// При включенной глубине резкости точка смещается в случайную точку диска, радиус которого растет
+// с расстоянием от плоскости фокуса, случайные числа берутся из генератора рабочего потока.
+func (c *Camera3D) Project(rng *rand.Rand, x, y, z float64) (projX, projY float64, ok bool) {
+	projX, _, projY, ok = c.ProjectStereo(rng, x, y, z, 0)
+
+	return projX, projY, ok
+}
+
+// ProjectStereo - проецирует точку для левого и правого глаза, смещенных вдоль оси x камеры на ±separation/2.
+// Кадры сдвигаются обратно так, чтобы точки плоскости z = 0 камеры не имели параллакса. Размытие глубины
+// резкости одно для обоих глаз.
+func (c *Camera3D) ProjectStereo(rng *rand.Rand, x, y, z, separation float64) (leftX, rightX, projY float64, ok bool) {
+	projX, projY, depth := c.rotate(x, y, z)
+
+	scale := 1 - c.Perspective*depth
+	if scale <= 0 {
+		return 0, 0, 0, false
+	}
+
+	if c.DOF != 0 {
+		radius := c.DOF * math.Abs(depth-c.Focus) * math.Sqrt(rng.Float64())
+		angle := 2 * math.Pi * rng.Float64()
+
+		projX += radius * math.Cos(angle)
+		projY += radius * math.Sin(angle)
+	}
+
+	eye := separation / 2
+
+	return (projX+eye)/scale - eye, (projX-eye)/scale + eye, projY / scale, true
+}
+
+// rotate - переводит точку в систему координат камеры: смещение по z, затем поворот по тангажу и рысканию.
+func (c *Camera3D) rotate(x, y, z float64) (rotX, rotY, depth float64) {
+	z -= c.ZPos
+
+	rotX = c.matrix[0][0]*x + c.matrix[0][1]*y
+	rotY = c.matrix[1][0]*x + c.matrix[1][1]*y + c.matrix[1][2]*z
+	depth = c.matrix[2][0]*x + c.matrix[2][1]*y + c.matrix[2][2]*z
+
+	return rotX, rotY, depth
+}
@@ -0,0 +1,86 @@
+package domain_test
+
+import (
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+const projectionTolerance = 1e-9
+
+func TestCamera3D_ProjectRotatesAndAppliesPerspective(t *testing.T) {
+	tests := []struct {
+		name         string
+		camera       *domain.Camera3D
+		point        [3]float64
+		wantX, wantY float64
+	}{
+		{"front view drops z", domain.NewCamera3D(0, 0, 0, 0), [3]float64{0.2, 0.5, 0.3}, 0.2, 0.5},
+		{"pitch 90 shows z", domain.NewCamera3D(90, 0, 0, 0), [3]float64{0.2, 0.5, 0.3}, 0.2, -0.3},
+		{"yaw 90 turns the plane", domain.NewCamera3D(0, 90, 0, 0), [3]float64{0.2, 0.5, 0.3}, 0.5, -0.2},
+		{"perspective enlarges near points", domain.NewCamera3D(0, 0, 0.5, 0), [3]float64{0.2, 0.5, 1}, 0.4, 1},
+		{"zpos moves the depth origin", domain.NewCamera3D(0, 0, 0.5, 1), [3]float64{0.2, 0.5, 1}, 0.2, 0.5},
+	}
+
+	for _, tt := range tests {
+		x, y, ok := tt.camera.Project(nil, tt.point[0], tt.point[1], tt.point[2])
+		if !ok || math.Abs(x-tt.wantX) > projectionTolerance || math.Abs(y-tt.wantY) > projectionTolerance {
+			t.Errorf("%s: projected to (%v, %v, %v), want (%v, %v)", tt.name, x, y, ok, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestCamera3D_ProjectDropsPointsBehindCamera(t *testing.T) {
+	if _, _, ok := domain.NewCamera3D(0, 0, 0.5, 0).Project(nil, 0, 0, 2.5); ok {
+		t.Error("point behind the camera was projected")
+	}
+}
+
+func TestCamera3D_DepthOfFieldGrowsWithDistanceFromFocus(t *testing.T) {
+	camera := domain.NewCamera3D(0, 0, 0, 0)
+	camera.DOF = 0.1
+	camera.Focus = 0.5
+
+	rng := random.New(1, 0)
+
+	for i := 0; i < 100; i++ {
+		x, y, _ := camera.Project(rng, 0.2, 0.3, 0.5)
+		if x != 0.2 || y != 0.3 {
+			t.Fatalf("point in the focal plane moved to (%v, %v)", x, y)
+		}
+
+		x, y, _ = camera.Project(rng, 0.2, 0.3, 2.5)
+		if shift := math.Hypot(x-0.2, y-0.3); shift > 0.2+projectionTolerance {
+			t.Fatalf("point 2 units from the focal plane moved by %v, more than the radius 0.2", shift)
+		}
+	}
+}
+
+func TestCamera_MapsKnownPointToPixel(t *testing.T) {
+	tests := []struct {
+		name   string
+		camera domain.Camera
+		want   [2]int
+	}{
+		{"default camera", domain.Camera{}, [2]int{130, 110}},
+		{"scale", domain.Camera{Scale: 50}, [2]int{115, 105}},
+		{"zoom doubles the scale", domain.Camera{Zoom: 1}, [2]int{160, 120}},
+		{"centre moves the frame", domain.Camera{CenterX: 0.1, CenterY: -0.2}, [2]int{120, 130}},
+		{"rotation around the centre", domain.Camera{CenterX: 0.1, Zoom: 1, Rotate: 90}, [2]int{120, 59}},
+	}
+
+	for _, tt := range tests {
+		im, tr := constantFlame(0.3025, 0.1025)
+		tr.Variations = []domain.Variation{{Name: "Linear", Weight: 1, Func: transformations.Linear}}
+		im.SetCamera(tt.camera)
+
+		renderPoints(im, 0, 1)
+
+		if hits := hitPixels(im); len(hits) != 1 || hits[0] != tt.want {
+			t.Errorf("%s: point (0.3025, 0.1025) hit pixels %v, want only %v", tt.name, hits, tt.want)
+		}
+	}
+}
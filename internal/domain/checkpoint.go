@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"encoding/binary"
+	"io"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// CheckpointVersion - версия формата контрольной точки.
+const CheckpointVersion = 1
+
+// checkpointMagic - сигнатура в начале файла контрольной точки.
+var checkpointMagic = [4]byte{'F', 'F', 'C', 'K'}
+
+// Progress - состояние рендера в контрольной точке: стартовые точки с номерами из [0; Completed) обработаны.
+// Генератор случайных чисел переинициализируется перед каждой стартовой точкой от seed и ее номера, поэтому
+// его состояние полностью задается seed из заголовка гистограммы и номером следующей точки Completed.
+type Progress struct {
+	Completed      int
+	StartingPoints int
+	Iterations     int
+}
+
+// checkpointFileHeader - заголовок контрольной точки в файле (little-endian), за ним следует гистограмма.
+type checkpointFileHeader struct {
+	Magic          [4]byte
+	Version        uint32
+	Completed      uint64
+	StartingPoints uint64
+	Iterations     uint64
+}
+
+// WriteCheckpoint - записывает контрольную точку: число обработанных стартовых точек completed и гистограмму,
+// накопленную по ним.
+func (im *ImageMatrix) WriteCheckpoint(w io.Writer, completed int, genomeHash uint64) error {
+	header := checkpointFileHeader{
+		Magic:          checkpointMagic,
+		Version:        CheckpointVersion,
+		Completed:      uint64(completed),
+		StartingPoints: uint64(im.StartingPoints),
+		Iterations:     uint64(im.Iterations),
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	return im.WriteHistogram(w, genomeHash)
+}
+
+// ReadCheckpoint - читает контрольную точку: гистограмму в новом буфере, ее заголовок и состояние рендера.
+func ReadCheckpoint(r io.Reader) (*ImageMatrix, HistogramHeader, Progress, error) {
+	var header checkpointFileHeader
+
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, HistogramHeader{}, Progress{}, errors.ErrCheckpoint{Reason: "reading header: " + err.Error()}
+	}
+
+	switch {
+	case header.Magic != checkpointMagic:
+		return nil, HistogramHeader{}, Progress{}, errors.ErrCheckpoint{Reason: "not a checkpoint file"}
+	case header.Version != CheckpointVersion:
+		return nil, HistogramHeader{}, Progress{}, errors.ErrCheckpoint{Reason: "unsupported version"}
+	case header.Completed > header.StartingPoints:
+		return nil, HistogramHeader{}, Progress{}, errors.ErrCheckpoint{Reason: "more completed starting points than planned"}
+	}
+
+	im, histogram, err := ReadHistogram(r)
+	if err != nil {
+		return nil, HistogramHeader{}, Progress{}, err
+	}
+
+	progress := Progress{
+		Completed:      int(header.Completed),
+		StartingPoints: int(header.StartingPoints),
+		Iterations:     int(header.Iterations),
+	}
+
+	return im, histogram, progress, nil
+}
+
+// Resume - переносит в буфер гистограмму контрольной точки, если она получена для того же пламени с теми же
+// параметрами рендера, и возвращает номер стартовой точки, с которой нужно продолжить. Буфер должен быть
+// пустым и настроенным так же, как при исходном рендере, включая камеру из заголовка контрольной точки.
+func (im *ImageMatrix) Resume(checkpoint *ImageMatrix, header HistogramHeader, progress Progress, genomeHash uint64) (int, error) {
+	if progress.StartingPoints != im.StartingPoints || progress.Iterations != im.Iterations {
+		return 0, errors.ErrCheckpointMismatch{Reason: "starting points or iterations differ from the configuration"}
+	}
+
+	if header.Seed != im.Seed {
+		return 0, errors.ErrCheckpointMismatch{Reason: "seed differs from the checkpoint"}
+	}
+
+	if err := im.HistogramHeader(genomeHash).CheckCompatible(header); err != nil {
+		return 0, errors.ErrCheckpointMismatch{Reason: err.Error()}
+	}
+
+	im.AddHistogram(checkpoint)
+
+	return progress.Completed, nil
+}
@@ -0,0 +1,81 @@
+package domain_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+)
+
+// interruptedCheckpoint - контрольная точка пламени с seed после обработки первых completed стартовых точек.
+func interruptedCheckpoint(t *testing.T, seed uint64, completed int) *bytes.Buffer {
+	t.Helper()
+
+	im := histogramFlame(seed)
+	renderPoints(im, 0, completed)
+
+	var buf bytes.Buffer
+	if err := im.WriteCheckpoint(&buf, completed, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf
+}
+
+func TestResume_MatchesUninterruptedRender(t *testing.T) {
+	checkpoint, header, progress, err := domain.ReadCheckpoint(interruptedCheckpoint(t, 3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := histogramFlame(3)
+
+	from, err := resumed.Resume(checkpoint, header, progress, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if from != 1 {
+		t.Fatalf("resumed from starting point %d, want 1", from)
+	}
+
+	renderPoints(resumed, from, resumed.StartingPoints)
+
+	uninterrupted := renderHistogram(3)
+	uninterrupted.ToneMap(2.2)
+	resumed.ToneMap(2.2)
+
+	for y := range uninterrupted.Pixels {
+		for x := range uninterrupted.Pixels[y] {
+			want, got := &uninterrupted.Pixels[y][x], &resumed.Pixels[y][x]
+			if want.HitRate != got.HitRate || want.Colour != got.Colour {
+				t.Fatalf("pixel (%d, %d): resumed %d hits %v, uninterrupted %d hits %v",
+					x, y, got.HitRate, got.Colour, want.HitRate, want.Colour)
+			}
+		}
+	}
+}
+
+func TestResume_RejectsDifferentRender(t *testing.T) {
+	checkpoint, header, progress, err := domain.ReadCheckpoint(interruptedCheckpoint(t, 3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	longer := histogramFlame(3)
+	longer.Iterations *= 2
+
+	otherSeed := histogramFlame(4)
+
+	for name, im := range map[string]*domain.ImageMatrix{"more iterations": longer, "other seed": otherSeed} {
+		if _, err := im.Resume(checkpoint, header, progress, 7); !errors.As(err, &domainErrors.ErrCheckpointMismatch{}) {
+			t.Errorf("%s: got %v, want ErrCheckpointMismatch", name, err)
+		}
+	}
+
+	if _, err := histogramFlame(3).Resume(checkpoint, header, progress, 8); !errors.As(err, &domainErrors.ErrCheckpointMismatch{}) {
+		t.Errorf("other genome: got %v, want ErrCheckpointMismatch", err)
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import "math"
+
+// visibleLevel - яркость, ниже которой пиксель не виден после квантования в 8 бит и не учитывается в оценке
+// шума.
+const visibleLevel = 1.0 / 255
+
+// Snapshot - копия гистограммы после Samples итераций, отдельная от буфера: рендер продолжается, а снимки,
+// сделанные после разного числа итераций, сравниваются для оценки шума.
+type Snapshot struct {
+	pixels  [][]snapshotPixel
+	Samples int
+}
+
+// snapshotPixel - число попаданий и сумма цветов пикселя в момент снимка.
+type snapshotPixel struct {
+	hits int
+	sum  [3]float64
+}
+
+// Snapshot - копирует гистограмму после samples итераций. Генераторы в это время должны стоять, чтобы снимок
+// соответствовал целому числу стартовых точек.
+func (im *ImageMatrix) Snapshot(samples int) *Snapshot {
+	pixels := make([][]snapshotPixel, len(im.Pixels))
+
+	for y := range im.Pixels {
+		pixels[y] = make([]snapshotPixel, len(im.Pixels[y]))
+
+		for x := range im.Pixels[y] {
+			pixels[y][x] = snapshotPixel{hits: im.Pixels[y][x].HitRate, sum: im.Pixels[y][x].colourSum}
+		}
+	}
+
+	return &Snapshot{pixels: pixels, Samples: samples}
+}
+
+// RelativeNoise - оценка относительного шума снимка после тональной компрессии gamma по разнице с более
+// ранним снимком previous того же рендера. Яркость зависит от логарифма числа попаданий, поэтому перед
+// сравнением гистограмма раннего снимка приводится к экспозиции позднего: попадания и суммы цветов умножаются
+// на отношение числа итераций. Шум - среднеквадратичная разница компонент видимых пикселей, деленная на их
+// среднеквадратичную яркость, так что редкие тусклые пиксели не заглушают основную структуру пламени.
+// Ошибка среднего по n сэмплам убывает как 1/√n, поэтому разница снимков после n1 и n2 итераций
+// в √((n2-n1)/n1) раз больше шума позднего снимка, на это она и масштабируется. Если сравнивать не с чем,
+// результат - +Inf.
+func (s *Snapshot) RelativeNoise(previous *Snapshot, gamma float64) float64 {
+	if previous == nil || previous.Samples <= 0 || s.Samples <= previous.Samples {
+		return math.Inf(1)
+	}
+
+	exposure := float64(s.Samples) / float64(previous.Samples)
+	var maxHits int
+
+	for y := range s.pixels {
+		for _, pixel := range s.pixels[y] {
+			maxHits = max(maxHits, pixel.hits)
+		}
+	}
+
+	maxDensity := maxLogDensity(maxHits)
+
+	var diff, level float64
+
+	for y := range s.pixels {
+		for x, pixel := range s.pixels[y] {
+			colour := toneMapPixel(float64(pixel.hits), pixel.sum, maxDensity, gamma)
+
+			brightness := max(colour[0], colour[1], colour[2], colour[3])
+			if brightness < visibleLevel {
+				continue
+			}
+
+			old := previous.pixels[y][x]
+			oldSum := [3]float64{old.sum[0] * exposure, old.sum[1] * exposure, old.sum[2] * exposure}
+			oldColour := toneMapPixel(float64(old.hits)*exposure, oldSum, maxDensity, gamma)
+
+			for c := range colour {
+				d := colour[c] - oldColour[c]
+				diff += d * d / float64(len(colour))
+			}
+
+			level += brightness * brightness
+		}
+	}
+
+	if level == 0 {
+		return math.Inf(1)
+	}
+
+	scale := math.Sqrt(float64(previous.Samples) / float64(s.Samples-previous.Samples))
+
+	return math.Sqrt(diff/level) * scale
+}
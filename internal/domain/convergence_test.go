@@ -0,0 +1,44 @@
+package domain_test
+
+import (
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+)
+
+// snapshotOf - снимок гистограммы по стартовым точкам из [from; to).
+func snapshotOf(from, to int) *domain.Snapshot {
+	im := histogramFlame(3)
+	renderPoints(im, from, to)
+
+	return im.Snapshot((to - from) * im.Iterations)
+}
+
+func TestSnapshot_RelativeNoiseMatchesIndependentRenders(t *testing.T) {
+	const points = 64
+
+	half, full := snapshotOf(0, points/2), snapshotOf(0, points)
+	estimate := full.RelativeNoise(half, 2.2)
+
+	// Снимок независимых точек не вложен в full: дисперсия разницы равна σ²(1/n1 + 1/n2), а не σ²(1/n1 - 1/n2),
+	// и оценка по нему в √3 раз больше шума full.
+	measured := full.RelativeNoise(snapshotOf(points, points+points/2), 2.2) / math.Sqrt(3)
+
+	if estimate <= 0 || estimate > 1.5*measured || measured > 1.5*estimate {
+		t.Errorf("estimated noise %.4f, independent renders give %.4f", estimate, measured)
+	}
+
+	if noise := full.RelativeNoise(full, 2.2); !math.IsInf(noise, 1) {
+		t.Errorf("noise against a snapshot with as many samples is %g, want +Inf", noise)
+	}
+}
+
+func TestSnapshot_NoiseDecreasesWithSamples(t *testing.T) {
+	early := snapshotOf(0, 16).RelativeNoise(snapshotOf(0, 8), 2.2)
+	late := snapshotOf(0, 128).RelativeNoise(snapshotOf(0, 64), 2.2)
+
+	if late >= early {
+		t.Errorf("noise after 128 starting points %.4f, after 16 - %.4f", late, early)
+	}
+}
@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type ErrOutPut struct {
 	Err error
@@ -17,6 +20,15 @@ func (err ErrZeroSizeMatrix) Error() string {
 	return "zero size matrix"
 }
 
+// ErrNoStopCondition - в конфигурации не задано ни число стартовых точек, ни ограничение по времени,
+// плотности или шуму, и рендер никогда не остановится.
+type ErrNoStopCondition struct {
+}
+
+func (err ErrNoStopCondition) Error() string {
+	return "no stop condition: set startingPoints, timeBudget, samplesPerPixel or noiseThreshold"
+}
+
 type ErrReadingConfig struct {
 	Err error
 }
@@ -32,3 +44,207 @@ type ErrSavingImage struct {
 func (err ErrSavingImage) Error() string {
 	return fmt.Sprintf("saving image error: %v", err.Err)
 }
+
+type ErrExpressionSyntax struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (err ErrExpressionSyntax) Error() string {
+	return fmt.Sprintf("expression syntax error at line %d, column %d: %s", err.Line, err.Column, err.Message)
+}
+
+type ErrExpressionRandomSource struct{}
+
+func (err ErrExpressionRandomSource) Error() string {
+	return "expression uses rand() but no random number generator is given"
+}
+
+type ErrCustomTransformation struct {
+	Name string
+	Err  error
+}
+
+func (err ErrCustomTransformation) Error() string {
+	return fmt.Sprintf("custom transformation %q: %v", err.Name, err.Err)
+}
+
+type ErrDuplicateTransformation struct {
+	Name string
+}
+
+func (err ErrDuplicateTransformation) Error() string {
+	return fmt.Sprintf("transformation %q is already registered", err.Name)
+}
+
+type ErrInvalidTransformationName struct {
+	Name string
+}
+
+func (err ErrInvalidTransformationName) Error() string {
+	return fmt.Sprintf("invalid transformation name %q: the name must be non-empty and have no leading or trailing spaces", err.Name)
+}
+
+type ErrUnknownTransformation struct {
+	Name  string
+	Known []string
+}
+
+func (err ErrUnknownTransformation) Error() string {
+	if len(err.Known) == 0 {
+		return fmt.Sprintf("unknown transformation %q", err.Name)
+	}
+
+	return fmt.Sprintf("unknown transformation %q, known: %s", err.Name, strings.Join(err.Known, ", "))
+}
+
+type ErrEmptyGenome struct {
+}
+
+func (err ErrEmptyGenome) Error() string {
+	return "genome has no xforms"
+}
+
+type ErrGenome struct {
+	Err error
+}
+
+func (err ErrGenome) Error() string {
+	return fmt.Sprintf("genome error: %v", err.Err)
+}
+
+type ErrUnknownWallpaperGroup struct {
+	Name string
+}
+
+func (err ErrUnknownWallpaperGroup) Error() string {
+	return fmt.Sprintf("unknown wallpaper group %q", err.Name)
+}
+
+type ErrHyperbolicTiling struct {
+	P int
+	Q int
+}
+
+func (err ErrHyperbolicTiling) Error() string {
+	return fmt.Sprintf("{%d,%d} is not a hyperbolic tiling, (p-2)(q-2) must be greater than 4", err.P, err.Q)
+}
+
+type ErrUnknownProjection struct {
+	Name string
+}
+
+func (err ErrUnknownProjection) Error() string {
+	return fmt.Sprintf("unknown projection %q", err.Name)
+}
+
+type ErrUnknownStereoLayout struct {
+	Name string
+}
+
+func (err ErrUnknownStereoLayout) Error() string {
+	return fmt.Sprintf("unknown stereo layout %q", err.Name)
+}
+
+type ErrStereoUnsupported struct {
+	Reason string
+}
+
+func (err ErrStereoUnsupported) Error() string {
+	return err.Reason
+}
+
+type ErrBackground struct {
+	Spec string
+}
+
+func (err ErrBackground) Error() string {
+	return fmt.Sprintf("invalid background %q, expected \"transparent\", \"#rrggbb\" or \"#rrggbb,#rrggbb\"", err.Spec)
+}
+
+type ErrBitDepth struct {
+	Depth int
+}
+
+func (err ErrBitDepth) Error() string {
+	return fmt.Sprintf("unsupported bit depth %d, expected 8 or 16", err.Depth)
+}
+
+type ErrDecoding struct {
+	Format string
+	Reason string
+}
+
+func (err ErrDecoding) Error() string {
+	return fmt.Sprintf("decoding %s: %s", err.Format, err.Reason)
+}
+
+type ErrEncoding struct {
+	Format string
+	Reason string
+}
+
+func (err ErrEncoding) Error() string {
+	return fmt.Sprintf("encoding %s: %s", err.Format, err.Reason)
+}
+
+type ErrHistogram struct {
+	Reason string
+}
+
+func (err ErrHistogram) Error() string {
+	return "invalid histogram file: " + err.Reason
+}
+
+type ErrHistogramVersion struct {
+	Version   uint32
+	Supported uint32
+}
+
+func (err ErrHistogramVersion) Error() string {
+	return fmt.Sprintf("histogram file version %d is not supported, expected version %d", err.Version, err.Supported)
+}
+
+type ErrUnknownCommand struct {
+	Name string
+}
+
+func (err ErrUnknownCommand) Error() string {
+	return fmt.Sprintf("unknown command %q", err.Name)
+}
+
+type ErrHistogramMismatch struct {
+	Index int
+	Field string
+	Want  string
+	Got   string
+}
+
+func (err ErrHistogramMismatch) Error() string {
+	return fmt.Sprintf("histogram #%d cannot be merged: %s differs, expected %s, got %s", err.Index+1, err.Field, err.Want, err.Got)
+}
+
+type ErrCheckpoint struct {
+	Reason string
+}
+
+func (err ErrCheckpoint) Error() string {
+	return "invalid checkpoint file: " + err.Reason
+}
+
+type ErrCheckpointMismatch struct {
+	Reason string
+}
+
+func (err ErrCheckpointMismatch) Error() string {
+	return "checkpoint does not match the configuration: " + err.Reason
+}
+
+type ErrTiledMode struct {
+	Reason string
+}
+
+func (err ErrTiledMode) Error() string {
+	return "tiled rendering: " + err.Reason
+}
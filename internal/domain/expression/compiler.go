@@ -0,0 +1,200 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+)
+
+type opcode uint8
+
+const (
+	opConst opcode = iota
+	opLoad
+	opNeg
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opPow
+	opSin
+	opCos
+	opTan
+	opSqrt
+	opAbs
+	opExp
+	opLog
+	opAtan2
+	opRand
+)
+
+// maxStackDepth - максимальная глубина стека вычислителя, более глубокие выражения отвергаются при компиляции.
+const maxStackDepth = 32
+
+type instruction struct {
+	op    opcode
+	index int
+	value float64
+}
+
+type function struct {
+	op    opcode
+	arity int
+}
+
+var functions = map[string]function{
+	"sin":   {op: opSin, arity: 1},
+	"cos":   {op: opCos, arity: 1},
+	"tan":   {op: opTan, arity: 1},
+	"sqrt":  {op: opSqrt, arity: 1},
+	"abs":   {op: opAbs, arity: 1},
+	"exp":   {op: opExp, arity: 1},
+	"log":   {op: opLog, arity: 1},
+	"atan2": {op: opAtan2, arity: 2},
+	"pow":   {op: opPow, arity: 2},
+	"rand":  {op: opRand, arity: 0},
+}
+
+var binaryOperators = map[string]opcode{
+	"+": opAdd,
+	"-": opSub,
+	"*": opMul,
+	"/": opDiv,
+	"^": opPow,
+}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+type compiler struct {
+	params map[string]float64
+	code   []instruction
+	depth  int
+	used   [numVariables]bool
+	random bool
+}
+
+// compile - переводит дерево выражения в линейную программу для стекового вычислителя, сворачивая константные
+// подвыражения.
+func (c *compiler) compile(n node) ([]instruction, error) {
+	c.code = nil
+	c.depth = 0
+
+	if err := c.emitNode(n); err != nil {
+		return nil, err
+	}
+
+	return c.code, nil
+}
+
+func (c *compiler) emitNode(n node) error {
+	switch n := n.(type) {
+	case numberNode:
+		return c.push(n.pos, instruction{op: opConst, value: n.value})
+	case identNode:
+		return c.emitIdent(n)
+	case unaryNode:
+		if err := c.emitNode(n.operand); err != nil {
+			return err
+		}
+
+		c.apply(opNeg, 1)
+	case binaryNode:
+		if err := c.emitNode(n.left); err != nil {
+			return err
+		}
+
+		if err := c.emitNode(n.right); err != nil {
+			return err
+		}
+
+		c.apply(binaryOperators[n.operator], 2)
+	case callNode:
+		return c.emitCall(n)
+	}
+
+	return nil
+}
+
+func (c *compiler) emitIdent(n identNode) error {
+	if index, ok := variableIndex(n.name); ok {
+		c.used[index] = true
+
+		return c.push(n.pos, instruction{op: opLoad, index: index})
+	}
+
+	if value, ok := c.params[n.name]; ok {
+		return c.push(n.pos, instruction{op: opConst, value: value})
+	}
+
+	if value, ok := constants[n.name]; ok {
+		return c.push(n.pos, instruction{op: opConst, value: value})
+	}
+
+	if _, ok := functions[n.name]; ok {
+		return syntaxError(n.pos, fmt.Sprintf("function %q must be called with parentheses", n.name))
+	}
+
+	return syntaxError(n.pos, fmt.Sprintf("unknown variable or parameter %q", n.name))
+}
+
+func (c *compiler) emitCall(n callNode) error {
+	fn, ok := functions[n.name]
+	if !ok {
+		return syntaxError(n.pos, fmt.Sprintf("unknown function %q", n.name))
+	}
+
+	if len(n.args) != fn.arity {
+		return syntaxError(n.pos, fmt.Sprintf("function %q expects %d argument(s), got %d", n.name, fn.arity, len(n.args)))
+	}
+
+	for _, arg := range n.args {
+		if err := c.emitNode(arg); err != nil {
+			return err
+		}
+	}
+
+	if fn.op == opRand {
+		c.random = true
+	}
+
+	if fn.arity == 0 {
+		return c.push(n.pos, instruction{op: fn.op})
+	}
+
+	c.apply(fn.op, fn.arity)
+
+	return nil
+}
+
+func (c *compiler) push(pos position, in instruction) error {
+	c.depth++
+	if c.depth > maxStackDepth {
+		return syntaxError(pos, "expression is too deeply nested")
+	}
+
+	c.code = append(c.code, in)
+
+	return nil
+}
+
+// apply - добавляет операцию над верхними arity значениями стека, если все аргументы константы, операция
+// выполняется сразу и заменяется результатом.
+func (c *compiler) apply(op opcode, arity int) {
+	c.depth -= arity - 1
+
+	args := c.code[len(c.code)-arity:]
+	for _, arg := range args {
+		if arg.op != opConst {
+			c.code = append(c.code, instruction{op: op})
+
+			return
+		}
+	}
+
+	folded := append(append([]instruction(nil), args...), instruction{op: op})
+	value := program(folded).run(nil, nil)
+
+	c.code = append(c.code[:len(c.code)-arity], instruction{op: opConst, value: value})
+}
@@ -0,0 +1,144 @@
+package expression_test
+
+import (
+	stderrors "errors"
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/expression"
+	"FractalFlame/pkg/random"
+)
+
+func TestCompile_Transform(t *testing.T) {
+	tc := []struct {
+		name   string
+		source string
+		params map[string]float64
+		x, y   float64
+		wantX  float64
+		wantY  float64
+	}{
+		{name: "linear", source: "x' = x\ny' = y", x: 0.3, y: -0.7, wantX: 0.3, wantY: -0.7},
+		{name: "precedence", source: "x' = 1 + 2 * x ^ 2; y' = -y ^ 2", x: 3, y: 2, wantX: 19, wantY: -4},
+		{name: "polar variables", source: "x' = r\ny' = theta + phi", x: 0, y: 2, wantX: 2, wantY: math.Pi / 2},
+		{name: "functions", source: "x' = pow(x, 3) + sqrt(y)\ny' = atan2(y, x) * cos(0)", x: 1, y: 4, wantX: 3,
+			wantY: math.Atan2(4, 1)},
+		{name: "params", source: "# spiral\nx' = a * x\ny' = y / b", params: map[string]float64{"a": 2, "b": 4}, x: 1,
+			y: 1, wantX: 2, wantY: 0.25},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			variation, err := expression.Compile(tt.source, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			gotX, gotY := variation.Transform(nil, tt.x, tt.y)
+			if math.Abs(gotX-tt.wantX) > 1e-9 || math.Abs(gotY-tt.wantY) > 1e-9 {
+				t.Errorf("Transform(%v, %v) = (%v, %v), want (%v, %v)", tt.x, tt.y, gotX, gotY, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestCompile_Rand(t *testing.T) {
+	variation, err := expression.Compile("x' = rand()\ny' = rand() - 1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rng, replay := random.New(7, 0), random.New(7, 0)
+
+	for i := 0; i < 100; i++ {
+		x, y := variation.Transform(rng, 0, 0)
+		if x < 0 || x >= 1 || y < -1 || y >= 0 {
+			t.Fatalf("rand() out of range: (%v, %v)", x, y)
+		}
+
+		if replayX, replayY := variation.Transform(replay, 0, 0); replayX != x || replayY != y {
+			t.Fatalf("rand() with the same seed gave (%v, %v), then (%v, %v)", x, y, replayX, replayY)
+		}
+	}
+}
+
+func TestEval_RandWithoutGenerator(t *testing.T) {
+	noisy, err := expression.Compile("x' = x + rand()\ny' = y", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !noisy.UsesRand() {
+		t.Error("UsesRand() = false for a transformation with rand()")
+	}
+
+	var missing errors.ErrExpressionRandomSource
+	if _, _, err := noisy.Eval(nil, 1, 2); !stderrors.As(err, &missing) {
+		t.Errorf("Eval with a nil generator = %v, want ErrExpressionRandomSource", err)
+	}
+
+	if x, _, err := noisy.Eval(random.New(1, 0), 1, 2); err != nil || x < 1 || x >= 2 {
+		t.Errorf("Eval with a generator = (%v, %v), want x in [1; 2)", x, err)
+	}
+
+	deterministic, err := expression.Compile("x' = 2 * x\ny' = y", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deterministic.UsesRand() {
+		t.Error("UsesRand() = true for a transformation without rand()")
+	}
+
+	if x, y, err := deterministic.Eval(nil, 1, 2); err != nil || x != 2 || y != 2 {
+		t.Errorf("Eval without rand() and generator = (%v, %v, %v), want (2, 2, nil)", x, y, err)
+	}
+}
+
+func TestCompile_SyntaxErrors(t *testing.T) {
+	tc := []struct {
+		name       string
+		source     string
+		wantLine   int
+		wantColumn int
+	}{
+		{name: "unknown character", source: "x' = x $ y\ny' = y", wantLine: 1, wantColumn: 8},
+		{name: "unclosed paren", source: "x' = x\ny' = (y + 1", wantLine: 2, wantColumn: 12},
+		{name: "unknown function", source: "x' = x\ny' = foo(y)", wantLine: 2, wantColumn: 6},
+		{name: "wrong arity", source: "x' = atan2(y)\ny' = y", wantLine: 1, wantColumn: 6},
+		{name: "unknown variable", source: "x' = x\n\ny' = y * k", wantLine: 3, wantColumn: 10},
+		{name: "missing output", source: "x' = x\n", wantLine: 2, wantColumn: 1},
+		{name: "duplicate output", source: "x' = x\nx' = y", wantLine: 2, wantColumn: 1},
+		{name: "missing assignment", source: "x = 1", wantLine: 1, wantColumn: 1},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := expression.Compile(tt.source, nil)
+
+			var syntaxErr errors.ErrExpressionSyntax
+			if !stderrors.As(err, &syntaxErr) {
+				t.Fatalf("expected syntax error, got %v", err)
+			}
+
+			if syntaxErr.Line != tt.wantLine || syntaxErr.Column != tt.wantColumn {
+				t.Errorf("error at %d:%d, want %d:%d (%v)", syntaxErr.Line, syntaxErr.Column, tt.wantLine,
+					tt.wantColumn, syntaxErr)
+			}
+		})
+	}
+}
+
+func BenchmarkVariation_Transform(b *testing.B) {
+	variation, err := expression.Compile("x' = r * sin(theta + r)\ny' = r * cos(theta - r) * pow(2, 3)", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		variation.Transform(nil, 0.5, 0.25)
+	}
+}
@@ -0,0 +1,192 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNewline
+	tokenNumber
+	tokenIdent
+	tokenTarget
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenAssign
+)
+
+type position struct {
+	line   int
+	column int
+}
+
+type token struct {
+	kind   tokenKind
+	text   string
+	number float64
+	pos    position
+}
+
+type lexer struct {
+	source []rune
+	offset int
+	pos    position
+}
+
+// tokenize - разбивает исходный текст на лексемы, отслеживая строку и столбец каждой из них.
+func tokenize(source string) ([]token, error) {
+	l := &lexer{source: []rune(source), pos: position{line: 1, column: 1}}
+
+	var tokens []token
+
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, tok)
+
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) peek() rune {
+	if l.offset >= len(l.source) {
+		return 0
+	}
+
+	return l.source[l.offset]
+}
+
+func (l *lexer) advance() rune {
+	r := l.source[l.offset]
+	l.offset++
+
+	if r == '\n' {
+		l.pos.line++
+		l.pos.column = 1
+	} else {
+		l.pos.column++
+	}
+
+	return r
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpacesAndComments()
+
+	start := l.pos
+
+	if l.offset >= len(l.source) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	r := l.peek()
+
+	switch {
+	case r == '\n' || r == ';':
+		l.advance()
+
+		return token{kind: tokenNewline, text: string(r), pos: start}, nil
+	case unicode.IsDigit(r) || r == '.':
+		return l.number(start)
+	case unicode.IsLetter(r) || r == '_':
+		return l.identifier(start), nil
+	}
+
+	l.advance()
+
+	switch r {
+	case '+', '-', '*', '/', '^':
+		return token{kind: tokenOperator, text: string(r), pos: start}, nil
+	case '(':
+		return token{kind: tokenLeftParen, text: "(", pos: start}, nil
+	case ')':
+		return token{kind: tokenRightParen, text: ")", pos: start}, nil
+	case ',':
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case '=':
+		return token{kind: tokenAssign, text: "=", pos: start}, nil
+	}
+
+	return token{}, syntaxError(start, fmt.Sprintf("unexpected character %q", r))
+}
+
+func (l *lexer) skipSpacesAndComments() {
+	for l.offset < len(l.source) {
+		r := l.peek()
+
+		switch {
+		case r == '#':
+			for l.offset < len(l.source) && l.peek() != '\n' {
+				l.advance()
+			}
+		case r != '\n' && unicode.IsSpace(r):
+			l.advance()
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) number(start position) (token, error) {
+	begin := l.offset
+
+	for l.offset < len(l.source) && (unicode.IsDigit(l.peek()) || l.peek() == '.') {
+		l.advance()
+	}
+
+	if l.offset < len(l.source) && (l.peek() == 'e' || l.peek() == 'E') {
+		l.advance()
+
+		if l.peek() == '+' || l.peek() == '-' {
+			l.advance()
+		}
+
+		for l.offset < len(l.source) && unicode.IsDigit(l.peek()) {
+			l.advance()
+		}
+	}
+
+	text := string(l.source[begin:l.offset])
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, syntaxError(start, fmt.Sprintf("malformed number %q", text))
+	}
+
+	return token{kind: tokenNumber, text: text, number: value, pos: start}, nil
+}
+
+func (l *lexer) identifier(start position) token {
+	begin := l.offset
+
+	for l.offset < len(l.source) && (unicode.IsLetter(l.peek()) || unicode.IsDigit(l.peek()) || l.peek() == '_') {
+		l.advance()
+	}
+
+	text := string(l.source[begin:l.offset])
+
+	if l.peek() == '\'' {
+		l.advance()
+
+		return token{kind: tokenTarget, text: text, pos: start}
+	}
+
+	return token{kind: tokenIdent, text: text, pos: start}
+}
+
+func syntaxError(pos position, message string) error {
+	return errors.ErrExpressionSyntax{Line: pos.line, Column: pos.column, Message: message}
+}
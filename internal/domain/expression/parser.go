@@ -0,0 +1,300 @@
+package expression
+
+import "fmt"
+
+type node interface {
+	position() position
+}
+
+type numberNode struct {
+	pos   position
+	value float64
+}
+
+type identNode struct {
+	pos  position
+	name string
+}
+
+type unaryNode struct {
+	pos     position
+	operand node
+}
+
+type binaryNode struct {
+	pos         position
+	operator    string
+	left, right node
+}
+
+type callNode struct {
+	pos  position
+	name string
+	args []node
+}
+
+func (n numberNode) position() position { return n.pos }
+func (n identNode) position() position  { return n.pos }
+func (n unaryNode) position() position  { return n.pos }
+func (n binaryNode) position() position { return n.pos }
+func (n callNode) position() position   { return n.pos }
+
+type assignment struct {
+	target token
+	value  node
+}
+
+type parser struct {
+	tokens []token
+	cursor int
+}
+
+// parse - строит список присваиваний вида x' = ... по исходному тексту.
+//
+// Грамматика:
+//
+//	program    = { statement ( "\n" | ";" ) }
+//	statement  = target "=" expression
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/") unary }
+//	unary      = "-" unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | ident | ident "(" [ expression { "," expression } ] ")" | "(" expression ")"
+func parse(source string) ([]assignment, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	var statements []assignment
+
+	for {
+		p.skipNewlines()
+
+		if p.current().kind == tokenEOF {
+			return statements, nil
+		}
+
+		statement, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+
+		statements = append(statements, statement)
+
+		if tok := p.current(); tok.kind != tokenNewline && tok.kind != tokenEOF {
+			return nil, unexpected(tok)
+		}
+	}
+}
+
+func (p *parser) current() token {
+	return p.tokens[p.cursor]
+}
+
+func (p *parser) consume() token {
+	tok := p.tokens[p.cursor]
+
+	if tok.kind != tokenEOF {
+		p.cursor++
+	}
+
+	return tok
+}
+
+func (p *parser) skipNewlines() {
+	for p.current().kind == tokenNewline {
+		p.consume()
+	}
+}
+
+func (p *parser) statement() (assignment, error) {
+	target := p.consume()
+	if target.kind != tokenTarget {
+		return assignment{}, syntaxError(target.pos, fmt.Sprintf("expected assignment like x' = ..., got %s", describe(target)))
+	}
+
+	if tok := p.consume(); tok.kind != tokenAssign {
+		return assignment{}, syntaxError(tok.pos, fmt.Sprintf("expected '=', got %s", describe(tok)))
+	}
+
+	value, err := p.expression()
+	if err != nil {
+		return assignment{}, err
+	}
+
+	return assignment{target: target, value: value}, nil
+}
+
+func (p *parser) expression() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("+", "-") {
+		operator := p.consume()
+
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryNode{pos: operator.pos, operator: operator.text, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("*", "/") {
+		operator := p.consume()
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = binaryNode{pos: operator.pos, operator: operator.text, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isOperator("-") {
+		operator := p.consume()
+
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return unaryNode{pos: operator.pos, operand: operand}, nil
+	}
+
+	return p.power()
+}
+
+func (p *parser) power() (node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.isOperator("^") {
+		return base, nil
+	}
+
+	operator := p.consume()
+
+	exponent, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	return binaryNode{pos: operator.pos, operator: operator.text, left: base, right: exponent}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.consume()
+
+	if tok.kind == tokenNumber {
+		return numberNode{pos: tok.pos, value: tok.number}, nil
+	}
+
+	if tok.kind == tokenIdent {
+		if p.current().kind == tokenLeftParen {
+			return p.call(tok)
+		}
+
+		return identNode{pos: tok.pos, name: tok.text}, nil
+	}
+
+	if tok.kind != tokenLeftParen {
+		return nil, unexpected(tok)
+	}
+
+	inner, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if closing := p.consume(); closing.kind != tokenRightParen {
+		return nil, syntaxError(closing.pos, fmt.Sprintf("expected ')', got %s", describe(closing)))
+	}
+
+	return inner, nil
+}
+
+func (p *parser) call(name token) (node, error) {
+	p.consume()
+
+	var args []node
+
+	if p.current().kind == tokenRightParen {
+		p.consume()
+
+		return callNode{pos: name.pos, name: name.text}, nil
+	}
+
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+
+		tok := p.consume()
+		if tok.kind == tokenRightParen {
+			return callNode{pos: name.pos, name: name.text, args: args}, nil
+		}
+
+		if tok.kind != tokenComma {
+			return nil, syntaxError(tok.pos, fmt.Sprintf("expected ',' or ')', got %s", describe(tok)))
+		}
+	}
+}
+
+func (p *parser) isOperator(operators ...string) bool {
+	tok := p.current()
+	if tok.kind != tokenOperator {
+		return false
+	}
+
+	for _, operator := range operators {
+		if tok.text == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unexpected(tok token) error {
+	return syntaxError(tok.pos, "unexpected "+describe(tok))
+}
+
+func describe(tok token) string {
+	if tok.kind == tokenEOF {
+		return "end of input"
+	}
+
+	if tok.kind == tokenNewline {
+		return "end of line"
+	}
+
+	if tok.kind == tokenTarget {
+		return fmt.Sprintf("%q", tok.text+"'")
+	}
+
+	return fmt.Sprintf("%q", tok.text)
+}
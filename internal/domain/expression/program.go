@@ -0,0 +1,209 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+const (
+	varX = iota
+	varY
+	varR
+	varTheta
+	varPhi
+	numVariables
+)
+
+// Переменные, доступные в выражениях: x, y - координаты точки, r - расстояние до начала координат,
+// theta = atan2(y, x) как во встроенных преобразованиях, phi = atan2(x, y) как в flam3.
+var variables = [numVariables]string{"x", "y", "r", "theta", "phi"}
+
+var targets = []string{"x", "y"}
+
+func variableIndex(name string) (int, bool) {
+	for i, variable := range variables {
+		if variable == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+type program []instruction
+
+// run - выполняет программу на стековой машине, стек фиксированного размера не требует выделений памяти.
+// rand() берет числа из генератора rng, поэтому результат воспроизводим при заданном seed. Программа с rand()
+// требует непустой rng.
+func (p program) run(rng *rand.Rand, vars *[numVariables]float64) float64 {
+	var stack [maxStackDepth]float64
+
+	top := -1
+
+	for _, in := range p {
+		switch in.op {
+		case opConst:
+			top++
+			stack[top] = in.value
+		case opLoad:
+			top++
+			stack[top] = vars[in.index]
+		case opRand:
+			top++
+			stack[top] = rng.Float64()
+		case opNeg:
+			stack[top] = -stack[top]
+		case opAdd:
+			top--
+			stack[top] += stack[top+1]
+		case opSub:
+			top--
+			stack[top] -= stack[top+1]
+		case opMul:
+			top--
+			stack[top] *= stack[top+1]
+		case opDiv:
+			top--
+			stack[top] /= stack[top+1]
+		case opPow:
+			top--
+			stack[top] = math.Pow(stack[top], stack[top+1])
+		case opAtan2:
+			top--
+			stack[top] = math.Atan2(stack[top], stack[top+1])
+		case opSin:
+			stack[top] = math.Sin(stack[top])
+		case opCos:
+			stack[top] = math.Cos(stack[top])
+		case opTan:
+			stack[top] = math.Tan(stack[top])
+		case opSqrt:
+			stack[top] = math.Sqrt(stack[top])
+		case opAbs:
+			stack[top] = math.Abs(stack[top])
+		case opExp:
+			stack[top] = math.Exp(stack[top])
+		case opLog:
+			stack[top] = math.Log(stack[top])
+		}
+	}
+
+	return stack[0]
+}
+
+// Variation - скомпилированное пользовательское нелинейное преобразование.
+type Variation struct {
+	outputs    []program
+	needRadius bool
+	needTheta  bool
+	needPhi    bool
+	random     bool
+}
+
+// Compile - разбирает и компилирует описание преобразования из присваиваний x' = ... и y' = ...,
+// params задает значения именованных параметров, которые подставляются как константы.
+func Compile(source string, params map[string]float64) (*Variation, error) {
+	statements, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiler{params: params}
+	outputs := make([]program, len(targets))
+
+	for _, statement := range statements {
+		index := -1
+
+		for i, target := range targets {
+			if statement.target.text == target {
+				index = i
+			}
+		}
+
+		if index < 0 {
+			return nil, syntaxError(statement.target.pos, fmt.Sprintf("unknown output %q", statement.target.text+"'"))
+		}
+
+		if outputs[index] != nil {
+			return nil, syntaxError(statement.target.pos, fmt.Sprintf("output %q is assigned twice", statement.target.text+"'"))
+		}
+
+		code, err := c.compile(statement.value)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs[index] = code
+	}
+
+	for i, target := range targets {
+		if outputs[i] == nil {
+			return nil, syntaxError(endOfSource(source), fmt.Sprintf("missing assignment for %q", target+"'"))
+		}
+	}
+
+	return &Variation{
+		outputs:    outputs,
+		needRadius: c.used[varR],
+		needTheta:  c.used[varTheta],
+		needPhi:    c.used[varPhi],
+		random:     c.random,
+	}, nil
+}
+
+// UsesRand - использует ли преобразование rand().
+func (v *Variation) UsesRand() bool {
+	return v.random
+}
+
+// Eval - применяет преобразование к точке и возвращает ошибку, если в нем есть rand(), а rng не задан.
+func (v *Variation) Eval(rng *rand.Rand, x, y float64) (newX, newY float64, err error) {
+	if v.random && rng == nil {
+		return 0, 0, errors.ErrExpressionRandomSource{}
+	}
+
+	newX, newY = v.Transform(rng, x, y)
+
+	return newX, newY, nil
+}
+
+// Transform - применяет преобразование к точке, сигнатура совпадает со встроенными преобразованиями.
+// Генераторы всегда передают rng рабочего потока; если rng может быть nil, а UsesRand истинно, используйте
+// Eval, иначе rand() вызовет панику.
+func (v *Variation) Transform(rng *rand.Rand, x, y float64) (newX, newY float64) {
+	var vars [numVariables]float64
+
+	vars[varX], vars[varY] = x, y
+
+	if v.needRadius {
+		vars[varR] = math.Sqrt(x*x + y*y)
+	}
+
+	if v.needTheta {
+		vars[varTheta] = math.Atan2(y, x)
+	}
+
+	if v.needPhi {
+		vars[varPhi] = math.Atan2(x, y)
+	}
+
+	return v.outputs[0].run(rng, &vars), v.outputs[1].run(rng, &vars)
+}
+
+func endOfSource(source string) position {
+	pos := position{line: 1, column: 1}
+
+	for _, r := range source {
+		if r == '\n' {
+			pos.line++
+			pos.column = 1
+		} else {
+			pos.column++
+		}
+	}
+
+	return pos
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"math"
+)
+
+// filterScratch - рабочие буферы фильтра. Буфер полосы тайлового рендера хранит их, чтобы фильтр не выделял
+// память для каждой полосы заново.
+type filterScratch struct {
+	channels [][4]float64
+	buffer   [][4]float64
+	frame    [][4]float64
+}
+
+// reuse - срез buf длины n. Если прежней памяти не хватает, выделяется новая емкостью не меньше capacity.
+// Старое содержимое не стирается.
+func reuse(buf *[][4]float64, n, capacity int) [][4]float64 {
+	if cap(*buf) < n {
+		*buf = make([][4]float64, n, max(n, capacity))
+	}
+
+	*buf = (*buf)[:n]
+
+	return *buf
+}
+
+// Filter - сглаживает изображение гауссовым фильтром радиуса radius пикселей. В бесшовном режиме ядро
+// заворачивается через края изображения, иначе крайние пиксели повторяются. Панорама фильтруется с учетом
+// широты, грани кубической карты и кадры стереопары - каждый отдельно.
+func (im *ImageMatrix) Filter(radius float64) {
+	if radius <= 0 {
+		return
+	}
+
+	scratch := im.scratch
+	if scratch == nil {
+		scratch = &filterScratch{}
+	}
+
+	kernel := gaussianKernel(radius)
+	width, height := im.Resolution.Width, im.Resolution.Height
+
+	// Буфер полосы после MoveBand может быть ниже, чем при создании. Память выделяется на все его строки,
+	// чтобы ее хватило и следующим полосам, а буфер свертки берет емкость у буфера каналов.
+	channels := reuse(&scratch.channels, width*height, width*cap(im.Pixels))
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			channels[y*width+x] = im.Pixels[y][x].Colour
+		}
+	}
+
+	switch im.projection {
+	case ProjectionEquirectangular:
+		filterEquirectangular(scratch, channels, radius, width, height)
+	case ProjectionCubemap:
+		filterFrames(scratch, channels, kernel, width, height, height, height, false)
+	default:
+		frameWidth, frameHeight := im.frame()
+		filterFrames(scratch, channels, kernel, width, height, frameWidth, frameHeight, im.Tileable)
+	}
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			im.Pixels[y][x].Colour = channels[y*width+x]
+		}
+	}
+}
+
+// filterPlane - разделимая свертка по строкам и столбцам прямоугольного изображения.
+func filterPlane(scratch *filterScratch, channels [][4]float64, kernel []float64, width, height int, wrapEdges bool) {
+	buffer := reuse(&scratch.buffer, width*height, cap(channels))
+
+	convolve(channels, buffer, kernel, width, height, 1, width, wrapEdges)
+	convolve(buffer, channels, kernel, height, width, width, 1, wrapEdges)
+}
+
+// filterEquirectangular - фильтр панорамы: строки заворачиваются по долготе, а радиус по горизонтали растет
+// как 1/cos(широты), потому что ближе к полюсам пиксель покрывает все меньший участок сферы.
+func filterEquirectangular(scratch *filterScratch, channels [][4]float64, radius float64, width, height int) {
+	buffer := reuse(&scratch.buffer, width*height, cap(channels))
+
+	for y := 0; y < height; y++ {
+		latitude := math.Pi/2 - (float64(y)+0.5)/float64(height)*math.Pi
+		rowRadius := math.Min(radius/math.Cos(latitude), float64(width)/2)
+		row := y * width
+
+		convolve(channels[row:row+width], buffer[row:row+width], gaussianKernel(rowRadius), width, 1, 1, width, true)
+	}
+
+	convolve(buffer, channels, gaussianKernel(radius), height, width, width, 1, false)
+}
+
+// filterFrames - фильтрует каждый кадр frameWidth x frameHeight изображения отдельно: грани кубической карты,
+// кадры стереопары или все изображение целиком.
+func filterFrames(scratch *filterScratch, channels [][4]float64, kernel []float64, width, height, frameWidth, frameHeight int,
+	wrapEdges bool) {
+	if frameWidth == width && frameHeight == height {
+		filterPlane(scratch, channels, kernel, width, height, wrapEdges)
+
+		return
+	}
+
+	frame := reuse(&scratch.frame, frameWidth*frameHeight, 0)
+
+	for top := 0; top < height; top += frameHeight {
+		for left := 0; left < width; left += frameWidth {
+			for y := 0; y < frameHeight; y++ {
+				row := (top+y)*width + left
+				copy(frame[y*frameWidth:(y+1)*frameWidth], channels[row:row+frameWidth])
+			}
+
+			filterPlane(scratch, frame, kernel, frameWidth, frameHeight, wrapEdges)
+
+			for y := 0; y < frameHeight; y++ {
+				row := (top+y)*width + left
+				copy(channels[row:row+frameWidth], frame[y*frameWidth:(y+1)*frameWidth])
+			}
+		}
+	}
+}
+
+// gaussianKernel - нормированное ядро гауссова фильтра, sigma равна половине радиуса.
+func gaussianKernel(radius float64) []float64 {
+	half := int(math.Ceil(radius))
+	sigma := radius / 2
+	kernel := make([]float64, 2*half+1)
+
+	var sum float64
+
+	for i := range kernel {
+		d := float64(i - half)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	return kernel
+}
+
+// convolve - одномерная свертка вдоль линий длины length с шагом step, линии идут с шагом lineStep.
+func convolve(src, dst [][4]float64, kernel []float64, length, lines, step, lineStep int, wrapEdges bool) {
+	half := len(kernel) / 2
+
+	for line := 0; line < lines; line++ {
+		base := line * lineStep
+
+		for i := 0; i < length; i++ {
+			var sum [4]float64
+
+			for k, weight := range kernel {
+				j := i + k - half
+
+				if wrapEdges {
+					j = ((j % length) + length) % length
+				} else {
+					j = min(max(j, 0), length-1)
+				}
+
+				v := src[base+j*step]
+				sum[0] += weight * v[0]
+				sum[1] += weight * v[1]
+				sum[2] += weight * v[2]
+				sum[3] += weight * v[3]
+			}
+
+			dst[base+i*step] = sum
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FloatBitDepth - глубина цвета результата, при которой изображение остается в числах с плавающей точкой
+// (FloatImage) для экспорта в HDR-форматы.
+const FloatBitDepth = 32
+
+// FloatImage - изображение с линейными компонентами R, G, B в числах с плавающей точкой без ограничения
+// сверху. Pix хранит компоненты строками сверху вниз, по три числа на пиксель.
+type FloatImage struct {
+	Pix  []float32
+	Rect image.Rectangle
+}
+
+// NewFloatImage - создает черное изображение размера rect.
+func NewFloatImage(rect image.Rectangle) *FloatImage {
+	return &FloatImage{Pix: make([]float32, 3*rect.Dx()*rect.Dy()), Rect: rect}
+}
+
+func (f *FloatImage) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (f *FloatImage) Bounds() image.Rectangle {
+	return f.Rect
+}
+
+// At - цвет пикселя, ограниченный диапазоном [0; 1], чтобы изображение можно было вывести в обычные форматы.
+func (f *FloatImage) At(x, y int) color.Color {
+	if !(image.Point{X: x, Y: y}.In(f.Rect)) {
+		return color.RGBA64{}
+	}
+
+	r, g, b := f.FloatAt(x, y)
+
+	return color.RGBA64{
+		R: uint16(quantize(float64(r), math.MaxUint16)),
+		G: uint16(quantize(float64(g), math.MaxUint16)),
+		B: uint16(quantize(float64(b), math.MaxUint16)),
+		A: math.MaxUint16,
+	}
+}
+
+// FloatAt - линейные компоненты пикселя.
+func (f *FloatImage) FloatAt(x, y int) (r, g, b float32) {
+	i := f.offset(x, y)
+
+	return f.Pix[i], f.Pix[i+1], f.Pix[i+2]
+}
+
+// SetFloat - задает линейные компоненты пикселя.
+func (f *FloatImage) SetFloat(x, y int, r, g, b float32) {
+	i := f.offset(x, y)
+	f.Pix[i], f.Pix[i+1], f.Pix[i+2] = r, g, b
+}
+
+func (f *FloatImage) offset(x, y int) int {
+	return 3 * ((y-f.Rect.Min.Y)*f.Rect.Dx() + x - f.Rect.Min.X)
+}
@@ -1,21 +1,43 @@
 package generator
 
 import (
+	"context"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"FractalFlame/internal/domain"
+	"FractalFlame/pkg/random"
 )
 
+// MultiThreadGenerator - многопоточный генератор. Если задан Progress, в нем отмечается каждая обработанная
+// стартовая точка, а пока идет RenderRange, его Report периодически получает состояние рендера.
 type MultiThreadGenerator struct {
 	NumWorkers int
+	Progress   *Progress
 }
 
-// Render функция, которая обеспечивает многопоточную генерацию фрактального пламени.
-func (m *MultiThreadGenerator) Render(im *domain.ImageMatrix) {
-	var wg sync.WaitGroup
+// Render функция, которая обеспечивает многопоточную генерацию фрактального пламени. Возвращает число
+// обработанных стартовых точек: при отмене ctx рендер останавливается раньше.
+// Отсчет Progress начинается заново.
+func (m *MultiThreadGenerator) Render(ctx context.Context, im *domain.ImageMatrix) int {
+	m.Progress.Start(im.StartingPoints, im.Iterations, 0)
 
-	jobs := make(chan int, im.StartingPoints)
+	return m.RenderRange(ctx, im, 0, im.StartingPoints)
+}
+
+// RenderRange - обрабатывает стартовые точки с номерами из [from; to) и возвращается, когда все они готовы
+// или ctx отменен. Рабочий поток проверяет ctx перед тем, как взять следующую точку из очереди, и дорабатывает
+// взятую, поэтому готовые точки всегда идут подряд с from, их число и возвращается.
+func (m *MultiThreadGenerator) RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int {
+	var (
+		wg   sync.WaitGroup
+		done atomic.Int64
+	)
+
+	jobs := make(chan int, max(to-from, 0))
+
+	defer m.Progress.track()()
 
 	if m.NumWorkers == 0 {
 		m.NumWorkers = runtime.NumCPU()
@@ -27,17 +49,28 @@ func (m *MultiThreadGenerator) Render(im *domain.ImageMatrix) {
 		go func() {
 			defer wg.Done()
 
-			for range jobs {
-				im.ProcessStartingPoint()
+			rng := random.NewWorkerRand()
+
+			for ctx.Err() == nil {
+				i, ok := <-jobs
+				if !ok {
+					return
+				}
+
+				im.ProcessStartingPoint(i, rng)
+				done.Add(1)
+				m.Progress.pointDone()
 			}
 		}()
 	}
 
-	for i := 0; i < im.StartingPoints; i++ {
+	for i := from; i < to; i++ {
 		jobs <- i
 	}
 
 	close(jobs)
 
 	wg.Wait()
+
+	return int(done.Load())
 }
@@ -1,14 +1,42 @@
 package generator
 
 import (
+	"context"
+
 	"FractalFlame/internal/domain"
+	"FractalFlame/pkg/random"
 )
 
-type SingleThreadGenerator struct{}
+// SingleThreadGenerator - однопоточный генератор. Если задан Progress, в нем отмечается каждая обработанная
+// стартовая точка, а пока идет RenderRange, его Report периодически получает состояние рендера.
+type SingleThreadGenerator struct {
+	Progress *Progress
+}
+
+// Render функция, которая обеспечивает генерацию фрактального пламени. Возвращает число обработанных
+// стартовых точек: при отмене ctx рендер останавливается раньше.
+// Отсчет Progress начинается заново.
+func (s *SingleThreadGenerator) Render(ctx context.Context, im *domain.ImageMatrix) int {
+	s.Progress.Start(im.StartingPoints, im.Iterations, 0)
+
+	return s.RenderRange(ctx, im, 0, im.StartingPoints)
+}
 
-// Render функция, которая обеспечивает генерацию фрактального пламени.
-func (s *SingleThreadGenerator) Render(im *domain.ImageMatrix) {
-	for i := 0; i < im.StartingPoints; i++ {
-		im.ProcessStartingPoint()
+// RenderRange - обрабатывает стартовые точки с номерами из [from; to) и возвращает, сколько из них готово.
+// При отмене ctx текущая точка дорабатывается, поэтому готовые точки всегда идут подряд с from.
+func (s *SingleThreadGenerator) RenderRange(ctx context.Context, im *domain.ImageMatrix, from, to int) int {
+	rng := random.NewWorkerRand()
+
+	defer s.Progress.track()()
+
+	for i := from; i < to; i++ {
+		if ctx.Err() != nil {
+			return i - from
+		}
+
+		im.ProcessStartingPoint(i, rng)
+		s.Progress.pointDone()
 	}
+
+	return max(to-from, 0)
 }
@@ -1,11 +1,14 @@
 package generator_test
 
 import (
+	"context"
 	"fmt"
-
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/expression"
 	"FractalFlame/internal/domain/generator"
 	"FractalFlame/internal/domain/transformations"
 )
@@ -34,7 +37,7 @@ func BenchmarkSingleThreadGenerator_Render(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				gn.Render(img)
+				gn.Render(context.Background(), img)
 			}
 		})
 	}
@@ -68,7 +71,7 @@ func BenchmarkMultiThreadGenerator_Render(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				gn.Render(img)
+				gn.Render(context.Background(), img)
 			}
 		})
 	}
@@ -103,7 +106,7 @@ func BenchmarkMultiThreadGenerator_Render8(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				gn.Render(img)
+				gn.Render(context.Background(), img)
 			}
 		})
 	}
@@ -138,7 +141,7 @@ func BenchmarkMultiThreadGenerator_Render16(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				gn.Render(img)
+				gn.Render(context.Background(), img)
 			}
 		})
 	}
@@ -173,8 +176,136 @@ func BenchmarkMultiThreadGenerator_Render24(b *testing.B) {
 			b.ResetTimer()
 
 			for i := 0; i < b.N; i++ {
-				gn.Render(img)
+				gn.Render(context.Background(), img)
 			}
 		})
 	}
 }
+
+func TestMultiThreadGenerator_CancelledRenderKeepsPrefix(t *testing.T) {
+	flame := func() *domain.ImageMatrix {
+		im := domain.NewImageMatrix(64, 48, 64, 20000)
+		im.Seed = 5
+		im.GenerateAffineTransformations()
+		im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Disc, transformations.Linear)
+
+		return im
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(5*time.Millisecond, cancel)
+
+	interrupted := flame()
+
+	done := (&generator.MultiThreadGenerator{NumWorkers: 4}).Render(ctx, interrupted)
+	if done == interrupted.StartingPoints {
+		t.Skip("render finished before cancellation")
+	}
+
+	prefix := flame()
+	(&generator.SingleThreadGenerator{}).RenderRange(context.Background(), prefix, 0, done)
+
+	for y := range prefix.Pixels {
+		for x := range prefix.Pixels[y] {
+			if prefix.Pixels[y][x].HitRate != interrupted.Pixels[y][x].HitRate {
+				t.Fatalf("cancelled render is not the first %d starting points: pixel (%d, %d) has %d hits, want %d",
+					done, x, y, interrupted.Pixels[y][x].HitRate, prefix.Pixels[y][x].HitRate)
+			}
+		}
+	}
+
+	if (&generator.SingleThreadGenerator{}).Render(ctx, flame()) != 0 {
+		t.Error("single-thread generator rendered starting points after cancellation")
+	}
+}
+
+func TestGenerators_ReportProgressSequentiallyUpToEveryStartingPoint(t *testing.T) {
+	var (
+		reports   []generator.ProgressReport
+		reporting atomic.Bool
+	)
+
+	progress := &generator.Progress{Interval: time.Millisecond, Report: func(report generator.ProgressReport) {
+		if !reporting.CompareAndSwap(false, true) {
+			t.Error("progress reports overlap")
+		}
+
+		reports = append(reports, report)
+		time.Sleep(100 * time.Microsecond)
+		reporting.Store(false)
+	}}
+
+	generators := map[string]interface {
+		Render(ctx context.Context, im *domain.ImageMatrix) int
+	}{
+		"single thread": &generator.SingleThreadGenerator{Progress: progress},
+		"multi thread":  &generator.MultiThreadGenerator{NumWorkers: 4, Progress: progress},
+	}
+
+	for name, gn := range generators {
+		t.Run(name, func(t *testing.T) {
+			reports = nil
+
+			im := domain.NewImageMatrix(32, 32, 64, 20000)
+			im.GenerateAffineTransformations()
+			im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Linear)
+
+			gn.Render(context.Background(), im)
+
+			if len(reports) < 2 {
+				t.Fatalf("got %d reports, want periodic reports and a final one", len(reports))
+			}
+
+			for i, report := range reports {
+				if report.Total != im.StartingPoints || i > 0 && report.Completed < reports[i-1].Completed {
+					t.Errorf("report %d: %d of %d starting points", i, report.Completed, report.Total)
+				}
+			}
+
+			last := reports[len(reports)-1]
+			if last.Completed != im.StartingPoints || last.IterationsPerSecond <= 0 || last.ETA != 0 {
+				t.Errorf("final report: %d of %d, %.0f it/s, ETA %v, want every point, positive speed and no time left",
+					last.Completed, last.Total, last.IterationsPerSecond, last.ETA)
+			}
+		})
+	}
+}
+
+func TestMultiThreadGenerator_SeededRenderWithRandomVariationsIsReproducible(t *testing.T) {
+	jitter, err := expression.Compile("x' = x + 0.1 * rand()\ny' = y - 0.1 * rand()", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flame := func() *domain.ImageMatrix {
+		im := domain.NewImageMatrix(64, 48, 32, 5000)
+		im.Seed = 11
+		im.Camera3D = domain.NewCamera3D(30, 20, 0.2, 0)
+		im.GenerateAffineTransformations()
+
+		for i := range im.LinearTransformations {
+			im.LinearTransformations[i].Variations = []domain.Variation{
+				{Name: "Julia3D", Weight: 0.5, Func3D: transformations.Julia3D},
+				{Name: "Jitter", Weight: 0.5, Func: jitter.Transform},
+			}
+		}
+
+		return im
+	}
+
+	first, second := flame(), flame()
+
+	(&generator.MultiThreadGenerator{NumWorkers: 4}).Render(context.Background(), first)
+	(&generator.MultiThreadGenerator{NumWorkers: 3}).Render(context.Background(), second)
+
+	first.ToneMap(2.2)
+	second.ToneMap(2.2)
+
+	for y := range first.Pixels {
+		for x := range first.Pixels[y] {
+			if first.Pixels[y][x].HitRate != second.Pixels[y][x].HitRate || first.Pixels[y][x].Colour != second.Pixels[y][x].Colour {
+				t.Fatalf("pixel (%d, %d) differs between two renders with the same seed", x, y)
+			}
+		}
+	}
+}
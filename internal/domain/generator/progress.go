@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultProgressInterval - период отчетов о ходе рендера, если Interval не задан.
+const defaultProgressInterval = 200 * time.Millisecond
+
+// ProgressReport - ход рендера: сколько стартовых точек из Total обработано, средняя скорость в итерациях
+// в секунду с начала отсчета и оценка оставшегося времени. Пока скорость неизвестна, оба поля нулевые.
+type ProgressReport struct {
+	Completed           int
+	Total               int
+	IterationsPerSecond float64
+	ETA                 time.Duration
+}
+
+// Progress - счетчик хода рендера. Рабочие потоки только увеличивают атомарный счетчик обработанных точек,
+// а Report вызывается из отдельной горутины раз в Interval и один раз в конце каждого RenderRange. Вызовы
+// Report не пересекаются. Один счетчик можно передавать в несколько вызовов RenderRange, тогда скорость
+// и оставшееся время считаются по всему рендеру. Методы nil-счетчика ничего не делают.
+type Progress struct {
+	Report   func(ProgressReport)
+	Interval time.Duration
+
+	completed  atomic.Int64
+	mutex      sync.Mutex
+	started    time.Time
+	total      int
+	iterations int
+	resumed    int
+}
+
+// Start - начинает отсчет рендера из total стартовых точек по iterations итераций. Первые completed точек
+// уже обработаны (например, восстановлены из контрольной точки) и в скорости не учитываются.
+func (p *Progress) Start(total, iterations, completed int) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.started = time.Now()
+	p.total = total
+	p.iterations = iterations
+	p.resumed = completed
+	p.completed.Store(int64(completed))
+}
+
+// pointDone - отмечает обработанную стартовую точку.
+func (p *Progress) pointDone() {
+	if p == nil {
+		return
+	}
+
+	p.completed.Add(1)
+}
+
+// track - запускает горутину, которая раз в Interval передает в Report текущее состояние. Возвращаемая
+// функция останавливает ее и сообщает итоговое состояние.
+func (p *Progress) track() (stop func()) {
+	if p == nil || p.Report == nil {
+		return func() {}
+	}
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.Report(p.report())
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+
+		p.Report(p.report())
+	}
+}
+
+func (p *Progress) report() ProgressReport {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	completed := int(p.completed.Load())
+	report := ProgressReport{Completed: completed, Total: p.total}
+
+	elapsed := time.Since(p.started)
+	done := completed - p.resumed
+
+	if done > 0 && elapsed > 0 {
+		report.IterationsPerSecond = float64(done) * float64(p.iterations) / elapsed.Seconds()
+		report.ETA = time.Duration(float64(elapsed) * float64(max(p.total-completed, 0)) / float64(done))
+	}
+
+	return report
+}
@@ -0,0 +1,293 @@
+package genome
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"image/color"
+	"os"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/transformations"
+)
+
+// Genome - сериализуемое описание пламени, по которому его можно отрисовать повторно.
+// Symmetry задает порядок симметрии (см. domain.ImageMatrix.AddSymmetry), преобразования симметрии
+// в xforms не сохраняются. Wallpaper задает группу симметрии обоев (см. domain.ImageMatrix.SetWallpaperGroup),
+// Hyperbolic - параметры {p, q} гиперболической мозаики (см. domain.ImageMatrix.SetHyperbolicTiling).
+type Genome struct {
+	Camera     *Camera    `json:"camera,omitempty"`
+	Symmetry   int        `json:"symmetry,omitempty"`
+	Wallpaper  *Wallpaper `json:"wallpaper,omitempty"`
+	Hyperbolic *[2]int    `json:"hyperbolic,omitempty"`
+	Xforms     []Xform    `json:"xforms"`
+}
+
+// Wallpaper - группа симметрии обоев и масштаб ее решетки.
+type Wallpaper struct {
+	Group string  `json:"group"`
+	Scale float64 `json:"scale"`
+}
+
+// Camera - параметры камеры: плоскость изображения и необязательная трехмерная часть.
+type Camera struct {
+	Center      [2]float64 `json:"center"`
+	Scale       float64    `json:"scale"`
+	Zoom        float64    `json:"zoom,omitempty"`
+	Rotate      float64    `json:"rotate,omitempty"`
+	Pitch       float64    `json:"pitch,omitempty"`
+	Yaw         float64    `json:"yaw,omitempty"`
+	Perspective float64    `json:"perspective,omitempty"`
+	ZPos        float64    `json:"zpos,omitempty"`
+	DOF         float64    `json:"dof,omitempty"`
+	Focus       float64    `json:"focus,omitempty"`
+}
+
+// Xform - описание одного аффинного преобразования вместе с его нелинейными преобразованиями.
+// Пустой список variations означает выбор случайного преобразования из общего набора конфигурации.
+type Xform struct {
+	Coefs      [6]float64  `json:"coefs"`
+	Colour     [3]uint8    `json:"colour"`
+	Pre        []Variation `json:"pre,omitempty"`
+	Variations []Variation `json:"variations,omitempty"`
+	Post       []Variation `json:"post,omitempty"`
+	PostCoefs  *[6]float64 `json:"postCoefs,omitempty"`
+	ZCoefs     *[4]float64 `json:"zCoefs,omitempty"`
+}
+
+// Variation - ссылка на зарегистрированное нелинейное преобразование по имени.
+type Variation struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+// Load - читает геном из JSON файла.
+func Load(filePath string) (*Genome, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.ErrGenome{Err: err}
+	}
+	defer file.Close()
+
+	var g Genome
+
+	if err := json.NewDecoder(file).Decode(&g); err != nil {
+		return nil, errors.ErrGenome{Err: err}
+	}
+
+	return &g, nil
+}
+
+// Save - записывает геном в JSON файл.
+func (g *Genome) Save(filePath string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return errors.ErrGenome{Err: err}
+	}
+
+	if err := os.WriteFile(filePath, data, 0o600); err != nil {
+		return errors.ErrGenome{Err: err}
+	}
+
+	return nil
+}
+
+// Hash - хеш FNV-64a JSON представления генома: совпадает у гистограмм одного пламени, отрисованных
+// с разными seed.
+func (g *Genome) Hash() uint64 {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write(data)
+
+	return h.Sum64()
+}
+
+// FromMatrix - экспортирует камеру и преобразования пламени в геном.
+func FromMatrix(im *domain.ImageMatrix) *Genome {
+	g := &Genome{Camera: exportCamera(im), Symmetry: im.Symmetry, Xforms: make([]Xform, 0, len(im.LinearTransformations))}
+
+	for i := range im.LinearTransformations {
+		tr := &im.LinearTransformations[i]
+		if tr.Symmetry {
+			continue
+		}
+
+		g.Xforms = append(g.Xforms, Xform{
+			Coefs:      [6]float64{tr.A, tr.B, tr.C, tr.D, tr.E, tr.F},
+			Colour:     [3]uint8{tr.TransformationColour.R, tr.TransformationColour.G, tr.TransformationColour.B},
+			Pre:        exportVariations(tr.PreVariations),
+			Variations: exportVariations(tr.Variations),
+			Post:       exportVariations(tr.PostVariations),
+			PostCoefs:  exportPostAffine(tr.Post),
+			ZCoefs:     exportZAffine(tr.Z),
+		})
+	}
+
+	if group, scale := im.WallpaperGroup(); group != "" {
+		g.Wallpaper = &Wallpaper{Group: group, Scale: scale}
+	}
+
+	if h := im.HyperbolicTiling(); h != nil {
+		g.Hyperbolic = &[2]int{h.P, h.Q}
+	}
+
+	return g
+}
+
+// Apply - импортирует камеру и преобразования генома в пламя, нелинейные преобразования ищутся в реестре
+// по имени (сначала среди трехмерных). Если камера в геноме не указана, остается текущая.
+func (g *Genome) Apply(im *domain.ImageMatrix) error {
+	if len(g.Xforms) == 0 {
+		return errors.ErrGenome{Err: errors.ErrEmptyGenome{}}
+	}
+
+	if g.Camera != nil {
+		g.Camera.apply(im)
+	}
+
+	affine := make([]domain.AffineTransformation, 0, len(g.Xforms))
+
+	for _, xf := range g.Xforms {
+		tr := domain.AffineTransformation{
+			A: xf.Coefs[0], B: xf.Coefs[1], C: xf.Coefs[2], D: xf.Coefs[3], E: xf.Coefs[4], F: xf.Coefs[5],
+			TransformationColour: color.RGBA{R: xf.Colour[0], G: xf.Colour[1], B: xf.Colour[2], A: 255},
+		}
+
+		if xf.PostCoefs != nil {
+			tr.Post = &domain.PostAffine{
+				A: xf.PostCoefs[0], B: xf.PostCoefs[1], C: xf.PostCoefs[2],
+				D: xf.PostCoefs[3], E: xf.PostCoefs[4], F: xf.PostCoefs[5],
+			}
+		}
+
+		if xf.ZCoefs != nil {
+			tr.Z = &domain.ZAffine{A: xf.ZCoefs[0], B: xf.ZCoefs[1], C: xf.ZCoefs[2], D: xf.ZCoefs[3]}
+		}
+
+		var err error
+
+		if tr.PreVariations, err = importVariations(xf.Pre); err != nil {
+			return err
+		}
+
+		if tr.Variations, err = importVariations(xf.Variations); err != nil {
+			return err
+		}
+
+		if tr.PostVariations, err = importVariations(xf.Post); err != nil {
+			return err
+		}
+
+		affine = append(affine, tr)
+	}
+
+	im.LinearTransformations = affine
+	im.Symmetry = 0
+	im.AddSymmetry(g.Symmetry)
+
+	if g.Wallpaper != nil {
+		if err := im.SetWallpaperGroup(g.Wallpaper.Group, g.Wallpaper.Scale); err != nil {
+			return err
+		}
+	}
+
+	if g.Hyperbolic != nil {
+		if err := im.SetHyperbolicTiling(g.Hyperbolic[0], g.Hyperbolic[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func exportCamera(im *domain.ImageMatrix) *Camera {
+	cam := im.Camera()
+
+	exported := &Camera{
+		Center: [2]float64{cam.CenterX, cam.CenterY},
+		Scale:  cam.Scale,
+		Zoom:   cam.Zoom,
+		Rotate: cam.Rotate,
+	}
+
+	if im.Camera3D != nil {
+		exported.Pitch = im.Camera3D.Pitch
+		exported.Yaw = im.Camera3D.Yaw
+		exported.Perspective = im.Camera3D.Perspective
+		exported.ZPos = im.Camera3D.ZPos
+		exported.DOF = im.Camera3D.DOF
+		exported.Focus = im.Camera3D.Focus
+	}
+
+	return exported
+}
+
+func (c *Camera) apply(im *domain.ImageMatrix) {
+	im.SetCamera(domain.Camera{CenterX: c.Center[0], CenterY: c.Center[1], Scale: c.Scale, Zoom: c.Zoom, Rotate: c.Rotate})
+
+	im.Camera3D = nil
+
+	if c.Pitch != 0 || c.Yaw != 0 || c.Perspective != 0 || c.ZPos != 0 || c.DOF != 0 {
+		im.Camera3D = domain.NewCamera3D(c.Pitch, c.Yaw, c.Perspective, c.ZPos)
+		im.Camera3D.DOF = c.DOF
+		im.Camera3D.Focus = c.Focus
+	}
+}
+
+func exportPostAffine(post *domain.PostAffine) *[6]float64 {
+	if post == nil || *post == domain.IdentityPostAffine {
+		return nil
+	}
+
+	return &[6]float64{post.A, post.B, post.C, post.D, post.E, post.F}
+}
+
+func exportZAffine(za *domain.ZAffine) *[4]float64 {
+	if za == nil {
+		return nil
+	}
+
+	return &[4]float64{za.A, za.B, za.C, za.D}
+}
+
+func exportVariations(variations []domain.Variation) []Variation {
+	if len(variations) == 0 {
+		return nil
+	}
+
+	exported := make([]Variation, 0, len(variations))
+	for _, v := range variations {
+		exported = append(exported, Variation{Name: v.Name, Weight: v.Weight})
+	}
+
+	return exported
+}
+
+func importVariations(variations []Variation) ([]domain.Variation, error) {
+	if len(variations) == 0 {
+		return nil, nil
+	}
+
+	imported := make([]domain.Variation, 0, len(variations))
+
+	for _, v := range variations {
+		if fn, ok := transformations.Lookup3D(v.Name); ok {
+			imported = append(imported, domain.Variation{Name: v.Name, Weight: v.Weight, Func3D: fn})
+
+			continue
+		}
+
+		fn, ok := transformations.Lookup(v.Name)
+		if !ok {
+			return nil, errors.ErrGenome{Err: errors.ErrUnknownTransformation{Name: v.Name, Known: transformations.Names()}}
+		}
+
+		imported = append(imported, domain.Variation{Name: v.Name, Weight: v.Weight, Func: fn})
+	}
+
+	return imported, nil
+}
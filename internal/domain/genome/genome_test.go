@@ -0,0 +1,100 @@
+package genome_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/genome"
+)
+
+func TestGenome_SaveLoadRoundTripKeepsVariationLists(t *testing.T) {
+	g := &genome.Genome{
+		Camera: &genome.Camera{Center: [2]float64{0.1, -0.2}, Scale: 150, Zoom: 0.5, Rotate: 30},
+		Xforms: []genome.Xform{
+			{
+				Coefs:      [6]float64{0.5, 0.1, -0.2, 0.4, 0.3, 0.1},
+				Colour:     [3]uint8{200, 80, 40},
+				Pre:        []genome.Variation{{Name: "Spherical", Weight: 1}, {Name: "Swirl", Weight: 0.3}},
+				Variations: []genome.Variation{{Name: "Swirl", Weight: 0.5}, {Name: "Linear3D", Weight: 0.5}},
+				Post:       []genome.Variation{{Name: "Disc", Weight: 1}},
+				PostCoefs:  &[6]float64{0, -1, 0, 1, 0, 0},
+				ZCoefs:     &[4]float64{0, 0, 0.5, 0.1},
+			},
+			{Coefs: [6]float64{-0.3, 0.2, 0.1, 0.6, -0.1, 0.2}, Colour: [3]uint8{10, 120, 250}},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "genome.json")
+	if err := g.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := genome.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := domain.NewImageMatrix(64, 48, 1, 1)
+	if err := loaded.Apply(im); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := im.LinearTransformations[0]
+	if len(tr.PreVariations) != 2 || tr.PreVariations[1].Name != "Swirl" || tr.PreVariations[1].Func == nil {
+		t.Errorf("pre-variations %+v were not imported in order", tr.PreVariations)
+	}
+
+	if len(tr.Variations) != 2 || tr.Variations[1].Func3D == nil || len(tr.PostVariations) != 1 {
+		t.Errorf("variations %+v and post-variations %+v were not imported", tr.Variations, tr.PostVariations)
+	}
+
+	exported := genome.FromMatrix(im)
+	if !reflect.DeepEqual(exported.Xforms, g.Xforms) || *exported.Camera != *g.Camera {
+		t.Errorf("exported genome %+v differs from the saved one %+v", exported, g)
+	}
+}
+
+func TestGenome_ApplyRejectsUnknownVariation(t *testing.T) {
+	g := &genome.Genome{Xforms: []genome.Xform{{Post: []genome.Variation{{Name: "NoSuchVariation", Weight: 1}}}}}
+
+	var genomeErr domainErrors.ErrGenome
+
+	err := g.Apply(domain.NewImageMatrix(8, 8, 1, 1))
+	if !errors.As(err, &genomeErr) {
+		t.Fatalf("error %v, want genome error", err)
+	}
+
+	unknown, ok := genomeErr.Err.(domainErrors.ErrUnknownTransformation)
+	if !ok || unknown.Name != "NoSuchVariation" || len(unknown.Known) == 0 {
+		t.Errorf("error %v, want unknown transformation with the list of known names", err)
+	}
+}
+
+func TestGenome_ApplyAcceptsJWildfireVariationNames(t *testing.T) {
+	g := &genome.Genome{Xforms: []genome.Xform{{
+		Coefs:      [6]float64{0.5, 0, 0, 0.5, 0, 0},
+		Pre:        []genome.Variation{{Name: "pre_blur", Weight: 1}, {Name: "pre_spherical", Weight: 1}},
+		Variations: []genome.Variation{{Name: "Linear", Weight: 1}},
+		Post:       []genome.Variation{{Name: "post_curl", Weight: 1}},
+	}}}
+
+	im := domain.NewImageMatrix(8, 8, 1, 1)
+	if err := g.Apply(im); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := im.LinearTransformations[0]
+	if len(tr.PreVariations) != 2 || tr.PreVariations[0].Func == nil || len(tr.PostVariations) != 1 ||
+		tr.PostVariations[0].Func == nil {
+		t.Errorf("pre-variations %+v and post-variations %+v were not imported", tr.PreVariations, tr.PostVariations)
+	}
+
+	if exported := genome.FromMatrix(im); !reflect.DeepEqual(exported.Xforms[0].Pre, g.Xforms[0].Pre) ||
+		!reflect.DeepEqual(exported.Xforms[0].Post, g.Xforms[0].Post) {
+		t.Errorf("exported xform %+v lost the JWildfire names of %+v", exported.Xforms[0], g.Xforms[0])
+	}
+}
@@ -0,0 +1,377 @@
+package domain
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// HistogramVersion - версия формата файла гистограммы, файлы других версий не читаются.
+const HistogramVersion = 1
+
+// histogramMagic - сигнатура в начале файла гистограммы.
+var histogramMagic = [4]byte{'F', 'F', 'H', 'G'}
+
+// HistogramHeader - заголовок файла гистограммы. По нему при повторной тональной компрессии восстанавливается
+// буфер изображения: размер, камера, проекция и расположение стереопары нужны фильтру и выводу. Oversample
+// всегда 1 - рендер идет без суперсэмплинга. GenomeHash позволяет убедиться, что гистограммы относятся к одному
+// пламени.
+type HistogramHeader struct {
+	Version    uint32
+	Width      int
+	Height     int
+	Oversample int
+	Camera     Camera
+	Projection Projection
+	Stereo     *Stereo
+	Tileable   bool
+	Seed       uint64
+	GenomeHash uint64
+}
+
+// histogramFileHeader - заголовок в том виде, в котором он записывается в файл (little-endian).
+type histogramFileHeader struct {
+	Magic         [4]byte
+	Version       uint32
+	Width         uint32
+	Height        uint32
+	Oversample    uint32
+	Projection    uint8
+	StereoLayout  uint8
+	Tileable      uint8
+	_             uint8
+	CenterX       float64
+	CenterY       float64
+	Scale         float64
+	Zoom          float64
+	Rotate        float64
+	EyeSeparation float64
+	Seed          uint64
+	GenomeHash    uint64
+	Encoding      uint32
+}
+
+// payloadGzip - данные пикселей сжаты gzip: большая часть пикселей обычно пуста.
+const payloadGzip = 1
+
+// payloadWriters - gzip-писатели данных пикселей. Состояние сжатия занимает около мегабайта, а тайловый рендер
+// сбрасывает гистограммы полос одну за другой, поэтому писатели используются повторно.
+var payloadWriters = sync.Pool{New: func() any {
+	payload, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+
+	return payload
+}}
+
+// HistogramHeader - заголовок гистограммы текущего буфера с хешем генома genomeHash.
+func (im *ImageMatrix) HistogramHeader(genomeHash uint64) HistogramHeader {
+	return HistogramHeader{
+		Version:    HistogramVersion,
+		Width:      im.Resolution.Width,
+		Height:     im.Resolution.Height,
+		Oversample: 1,
+		Camera:     im.camera,
+		Projection: im.projection,
+		Stereo:     im.stereo,
+		Tileable:   im.Tileable,
+		Seed:       im.Seed,
+		GenomeHash: genomeHash,
+	}
+}
+
+// WriteHistogram - записывает накопленную гистограмму: заголовок и для каждого пикселя число попаданий и суммы
+// цветов. Тональная компрессия, фильтр и формат вывода в файл не входят и задаются при чтении.
+func (im *ImageMatrix) WriteHistogram(w io.Writer, genomeHash uint64) error {
+	header := im.HistogramHeader(genomeHash)
+	fileHeader := histogramFileHeader{
+		Magic:      histogramMagic,
+		Version:    header.Version,
+		Width:      uint32(header.Width),
+		Height:     uint32(header.Height),
+		Oversample: uint32(header.Oversample),
+		Projection: uint8(header.Projection),
+		CenterX:    header.Camera.CenterX,
+		CenterY:    header.Camera.CenterY,
+		Scale:      header.Camera.Scale,
+		Zoom:       header.Camera.Zoom,
+		Rotate:     header.Camera.Rotate,
+		Seed:       header.Seed,
+		GenomeHash: header.GenomeHash,
+		Encoding:   payloadGzip,
+	}
+
+	if header.Stereo != nil {
+		fileHeader.StereoLayout = uint8(header.Stereo.Layout)
+		fileHeader.EyeSeparation = header.Stereo.Separation
+	}
+
+	if header.Tileable {
+		fileHeader.Tileable = 1
+	}
+
+	if err := binary.Write(w, binary.LittleEndian, &fileHeader); err != nil {
+		return err
+	}
+
+	payload := payloadWriters.Get().(*gzip.Writer)
+	defer payloadWriters.Put(payload)
+
+	payload.Reset(w)
+
+	out := bufio.NewWriter(payload)
+
+	var record [32]byte
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			pixel := &im.Pixels[y][x]
+
+			binary.LittleEndian.PutUint64(record[0:], uint64(pixel.HitRate))
+			binary.LittleEndian.PutUint64(record[8:], math.Float64bits(pixel.colourSum[0]))
+			binary.LittleEndian.PutUint64(record[16:], math.Float64bits(pixel.colourSum[1]))
+			binary.LittleEndian.PutUint64(record[24:], math.Float64bits(pixel.colourSum[2]))
+
+			if _, err := out.Write(record[:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	return payload.Close()
+}
+
+// ReadHistogram - читает гистограмму в новый буфер изображения с той же камерой, проекцией и стереопарой,
+// готовый к тональной компрессии.
+func ReadHistogram(r io.Reader) (*ImageMatrix, HistogramHeader, error) {
+	header, err := readHistogramHeader(r)
+	if err != nil {
+		return nil, HistogramHeader{}, err
+	}
+
+	im := NewImageMatrix(header.Width, header.Height, 0, 0)
+	im.projection = header.Projection
+	im.stereo = header.Stereo
+	im.Tileable = header.Tileable
+	im.Seed = header.Seed
+	im.SetCamera(header.Camera)
+
+	if err := im.readPixels(r); err != nil {
+		return nil, HistogramHeader{}, err
+	}
+
+	return im, header, nil
+}
+
+// LoadHistogram - читает гистограмму того же размера в этот буфер, заменяя накопленные данные. Камера
+// и остальные параметры буфера не меняются.
+func (im *ImageMatrix) LoadHistogram(r io.Reader) (HistogramHeader, error) {
+	header, err := readHistogramHeader(r)
+	if err != nil {
+		return HistogramHeader{}, err
+	}
+
+	if header.Width != im.Resolution.Width || header.Height != len(im.Pixels) {
+		return HistogramHeader{}, errors.ErrHistogram{Reason: "size differs from the image buffer"}
+	}
+
+	return header, im.readPixels(r)
+}
+
+// readPixels - читает сжатые данные пикселей гистограммы.
+func (im *ImageMatrix) readPixels(r io.Reader) error {
+	payload, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.ErrHistogram{Reason: err.Error()}
+	}
+
+	in := bufio.NewReader(payload)
+
+	var record [32]byte
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			if _, err := io.ReadFull(in, record[:]); err != nil {
+				return errors.ErrHistogram{Reason: "truncated pixel data: " + err.Error()}
+			}
+
+			pixel := &im.Pixels[y][x]
+			pixel.HitRate = int(binary.LittleEndian.Uint64(record[0:]))
+			pixel.colourSum[0] = math.Float64frombits(binary.LittleEndian.Uint64(record[8:]))
+			pixel.colourSum[1] = math.Float64frombits(binary.LittleEndian.Uint64(record[16:]))
+			pixel.colourSum[2] = math.Float64frombits(binary.LittleEndian.Uint64(record[24:]))
+		}
+	}
+
+	return nil
+}
+
+func readHistogramHeader(r io.Reader) (HistogramHeader, error) {
+	var fileHeader histogramFileHeader
+
+	if err := binary.Read(r, binary.LittleEndian, &fileHeader); err != nil {
+		return HistogramHeader{}, errors.ErrHistogram{Reason: "reading header: " + err.Error()}
+	}
+
+	switch {
+	case fileHeader.Magic != histogramMagic:
+		return HistogramHeader{}, errors.ErrHistogram{Reason: "not a histogram file"}
+	case fileHeader.Version != HistogramVersion:
+		return HistogramHeader{}, errors.ErrHistogramVersion{Version: fileHeader.Version, Supported: HistogramVersion}
+	case fileHeader.Encoding != payloadGzip:
+		return HistogramHeader{}, errors.ErrHistogram{Reason: "unknown pixel data encoding"}
+	case fileHeader.Width == 0 || fileHeader.Height == 0:
+		return HistogramHeader{}, errors.ErrHistogram{Reason: "invalid image size"}
+	}
+
+	header := HistogramHeader{
+		Version:    fileHeader.Version,
+		Width:      int(fileHeader.Width),
+		Height:     int(fileHeader.Height),
+		Oversample: int(fileHeader.Oversample),
+		Camera: Camera{
+			CenterX: fileHeader.CenterX,
+			CenterY: fileHeader.CenterY,
+			Scale:   fileHeader.Scale,
+			Zoom:    fileHeader.Zoom,
+			Rotate:  fileHeader.Rotate,
+		},
+		Projection: Projection(fileHeader.Projection),
+		Tileable:   fileHeader.Tileable != 0,
+		Seed:       fileHeader.Seed,
+		GenomeHash: fileHeader.GenomeHash,
+	}
+
+	if fileHeader.StereoLayout != 0 {
+		header.Stereo = &Stereo{Separation: fileHeader.EyeSeparation, Layout: StereoLayout(fileHeader.StereoLayout)}
+	}
+
+	return header, nil
+}
+
+// CheckCompatible - проверяет, что гистограмму с заголовком other можно сложить с этой: совпадают размер,
+// oversample, камера, проекция, стереопара, бесшовный режим и геном. Seed может (и должен) отличаться.
+func (h HistogramHeader) CheckCompatible(other HistogramHeader) error {
+	var stereo, otherStereo Stereo
+
+	if h.Stereo != nil {
+		stereo = *h.Stereo
+	}
+
+	if other.Stereo != nil {
+		otherStereo = *other.Stereo
+	}
+
+	mismatch := func(field string, want, got any) error {
+		return errors.ErrHistogramMismatch{Field: field, Want: fmt.Sprint(want), Got: fmt.Sprint(got)}
+	}
+
+	switch {
+	case h.Width != other.Width || h.Height != other.Height:
+		return mismatch("size", fmt.Sprintf("%dx%d", h.Width, h.Height), fmt.Sprintf("%dx%d", other.Width, other.Height))
+	case h.Oversample != other.Oversample:
+		return mismatch("oversample", h.Oversample, other.Oversample)
+	case h.Camera != other.Camera:
+		return mismatch("camera", h.Camera, other.Camera)
+	case h.Projection != other.Projection:
+		return mismatch("projection", h.Projection, other.Projection)
+	case stereo != otherStereo:
+		return mismatch("stereo", stereo, otherStereo)
+	case h.Tileable != other.Tileable:
+		return mismatch("tileable", h.Tileable, other.Tileable)
+	case h.GenomeHash != other.GenomeHash:
+		return mismatch("genome hash", fmt.Sprintf("%016x", h.GenomeHash), fmt.Sprintf("%016x", other.GenomeHash))
+	}
+
+	return nil
+}
+
+// AddHistogram - прибавляет к гистограмме число попаданий и суммы цветов буфера other того же размера.
+func (im *ImageMatrix) AddHistogram(other *ImageMatrix) {
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			pixel, src := &im.Pixels[y][x], &other.Pixels[y][x]
+
+			pixel.HitRate += src.HitRate
+			pixel.colourSum[0] += src.colourSum[0]
+			pixel.colourSum[1] += src.colourSum[1]
+			pixel.colourSum[2] += src.colourSum[2]
+		}
+	}
+}
+
+// CopyHistogram - копия накопленной гистограммы в новом буфере с той же камерой, проекцией, стереопарой
+// и параметрами вывода. Преобразования не копируются: копия годится для тональной компрессии и сохранения, пока
+// рендер продолжается в исходном буфере. Генераторы во время копирования должны стоять.
+func (im *ImageMatrix) CopyHistogram() *ImageMatrix {
+	cp := NewImageMatrix(im.Resolution.Width, im.Resolution.Height, 0, 0)
+	cp.projection = im.projection
+	cp.stereo = im.stereo
+	cp.Tileable = im.Tileable
+	cp.Seed = im.Seed
+	cp.Camera3D = im.Camera3D
+	cp.Background = im.Background
+	cp.PremultipliedAlpha = im.PremultipliedAlpha
+	cp.BitDepth = im.BitDepth
+	cp.SetCamera(im.camera)
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			cp.Pixels[y][x].copyFrom(&im.Pixels[y][x])
+		}
+	}
+
+	return cp
+}
+
+// SamplesPerPixel - среднее число попаданий на пиксель: мера качества рендера, от которой зависит шум.
+func (im *ImageMatrix) SamplesPerPixel() float64 {
+	var hits int
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			hits += im.Pixels[y][x].HitRate
+		}
+	}
+
+	return float64(hits) / float64(im.Resolution.Width*im.Resolution.Height)
+}
+
+// MergeHistograms - читает несколько гистограмм одного пламени и складывает их в один буфер, заголовок берется
+// из первой. Несовместимая гистограмма дает ErrHistogramMismatch с ее номером.
+func MergeHistograms(readers ...io.Reader) (*ImageMatrix, HistogramHeader, error) {
+	if len(readers) == 0 {
+		return nil, HistogramHeader{}, errors.ErrHistogram{Reason: "nothing to merge"}
+	}
+
+	merged, header, err := ReadHistogram(readers[0])
+	if err != nil {
+		return nil, HistogramHeader{}, err
+	}
+
+	for i, r := range readers[1:] {
+		im, other, err := ReadHistogram(r)
+		if err != nil {
+			return nil, HistogramHeader{}, err
+		}
+
+		if err := header.CheckCompatible(other); err != nil {
+			mismatch, _ := err.(errors.ErrHistogramMismatch)
+			mismatch.Index = i + 1
+
+			return nil, HistogramHeader{}, mismatch
+		}
+
+		merged.AddHistogram(im)
+	}
+
+	return merged, header, nil
+}
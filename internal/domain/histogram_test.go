@@ -0,0 +1,203 @@
+package domain_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/genome"
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+// histogramFlame - небольшое пламя с seed, готовое к рендеру.
+func histogramFlame(seed uint64) *domain.ImageMatrix {
+	return setUpFlame(domain.NewImageMatrix(48, 32, 4, 5000), seed)
+}
+
+// setUpFlame - задает буферу seed, камеру и преобразования небольшого тестового пламени.
+func setUpFlame(im *domain.ImageMatrix, seed uint64) *domain.ImageMatrix {
+	im.Seed = seed
+	im.SetCamera(domain.Camera{Zoom: 0.5, Rotate: 30})
+	im.GenerateAffineTransformations()
+	im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Linear, transformations.Disc)
+
+	return im
+}
+
+// renderPoints - обрабатывает стартовые точки с номерами из [from; to).
+func renderPoints(im *domain.ImageMatrix, from, to int) {
+	rng := random.NewWorkerRand()
+	for i := from; i < to; i++ {
+		im.ProcessStartingPoint(i, rng)
+	}
+}
+
+func renderHistogram(seed uint64) *domain.ImageMatrix {
+	im := histogramFlame(seed)
+	renderPoints(im, 0, im.StartingPoints)
+
+	return im
+}
+
+func TestHistogram_RoundTripReproducesToneMapping(t *testing.T) {
+	im := renderHistogram(42)
+
+	var buf bytes.Buffer
+	if err := im.WriteHistogram(&buf, 0xfeed); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, header, err := domain.ReadHistogram(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Width != 48 || header.Height != 32 || header.GenomeHash != 0xfeed || header.Seed != 42 {
+		t.Errorf("header %+v does not match the render", header)
+	}
+
+	if loaded.Camera() != im.Camera() {
+		t.Errorf("camera %+v, want %+v", loaded.Camera(), im.Camera())
+	}
+
+	im.ToneMap(2.2)
+	loaded.ToneMap(2.2)
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			if im.Pixels[y][x].HitRate != loaded.Pixels[y][x].HitRate || im.Pixels[y][x].Colour != loaded.Pixels[y][x].Colour {
+				t.Fatalf("pixel (%d, %d) differs after reloading", x, y)
+			}
+		}
+	}
+}
+
+func TestReadHistogram_RejectsOtherVersionsAndTruncatedFiles(t *testing.T) {
+	var buf bytes.Buffer
+	if err := renderHistogram(1).WriteHistogram(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+
+	newer := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(newer[4:], domain.HistogramVersion+1)
+
+	if _, _, err := domain.ReadHistogram(bytes.NewReader(newer)); !errors.As(err, &domainErrors.ErrHistogramVersion{}) {
+		t.Errorf("newer version: got %v, want ErrHistogramVersion", err)
+	}
+
+	if _, _, err := domain.ReadHistogram(bytes.NewReader(data[:len(data)/2])); !errors.As(err, &domainErrors.ErrHistogram{}) {
+		t.Errorf("truncated file: got %v, want ErrHistogram", err)
+	}
+}
+
+func writeHistogram(t *testing.T, im *domain.ImageMatrix, genomeHash uint64) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := im.WriteHistogram(&buf, genomeHash); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf
+}
+
+// renderRun - рендер одного и того же пламени (преобразования, полученные при seed 1) с другим seed игры хаоса,
+// как при распределенном рендере на нескольких машинах.
+func renderRun(seed uint64) *domain.ImageMatrix {
+	im := histogramFlame(1)
+	im.Seed = seed
+	renderPoints(im, 0, im.StartingPoints)
+
+	return im
+}
+
+func TestMergeHistograms_SumsCountsOfSeparateRuns(t *testing.T) {
+	first, second := renderRun(1), renderRun(2)
+
+	hash := genome.FromMatrix(first).Hash()
+	if hash != genome.FromMatrix(second).Hash() {
+		t.Fatal("runs with different chaos game seeds have different genomes")
+	}
+
+	merged, header, err := domain.MergeHistograms(writeHistogram(t, first, hash), writeHistogram(t, second, hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Seed != 1 {
+		t.Errorf("merged header seed %d, want the seed of the first histogram", header.Seed)
+	}
+
+	var total, want int
+
+	for y := range merged.Pixels {
+		for x := range merged.Pixels[y] {
+			got := merged.Pixels[y][x].HitRate
+			if sum := first.Pixels[y][x].HitRate + second.Pixels[y][x].HitRate; got != sum {
+				t.Fatalf("pixel (%d, %d): %d hits, want %d", x, y, got, sum)
+			}
+
+			total += got
+			want += first.Pixels[y][x].HitRate
+		}
+	}
+
+	if total <= want {
+		t.Errorf("merged histogram has %d hits, the first run alone has %d", total, want)
+	}
+
+	if spp, sum := merged.SamplesPerPixel(), first.SamplesPerPixel()+second.SamplesPerPixel(); math.Abs(spp-sum) > 1e-9 {
+		t.Errorf("merged histogram has %g samples per pixel, want %g", spp, sum)
+	}
+}
+
+func TestMergeHistograms_ReportsIncompatibleHeaders(t *testing.T) {
+	moved := renderHistogram(2)
+	moved.SetCamera(domain.Camera{Zoom: 1})
+
+	cases := []struct {
+		name  string
+		other *bytes.Buffer
+		field string
+	}{
+		{name: "other genome", other: writeHistogram(t, renderHistogram(2), 8), field: "genome hash"},
+		{name: "other camera", other: writeHistogram(t, moved, 7), field: "camera"},
+		{name: "other size", other: writeHistogram(t, domain.NewImageMatrix(32, 32, 1, 1), 7), field: "size"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := domain.MergeHistograms(writeHistogram(t, renderHistogram(1), 7), tc.other)
+
+			var mismatch domainErrors.ErrHistogramMismatch
+			if !errors.As(err, &mismatch) || mismatch.Field != tc.field || mismatch.Index != 1 {
+				t.Errorf("got %v, want mismatch of %s in the second histogram", err, tc.field)
+			}
+		})
+	}
+}
+
+func TestCopyHistogram_IsIndependentOfFurtherRendering(t *testing.T) {
+	im := histogramFlame(4)
+	renderPoints(im, 0, 2)
+
+	copied := im.CopyHistogram()
+	want := im.SamplesPerPixel()
+
+	renderPoints(im, 2, im.StartingPoints)
+
+	if got := copied.SamplesPerPixel(); got != want || got >= im.SamplesPerPixel() {
+		t.Errorf("copy has %g samples per pixel, want %g at the time of copying", got, want)
+	}
+
+	if copied.Camera() != im.Camera() || copied.Projection() != im.Projection() {
+		t.Error("copy does not keep the camera and projection of the render")
+	}
+}
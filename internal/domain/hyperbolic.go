@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// Hyperbolic - параметры гиперболической мозаики {P, Q}: в каждой вершине сходятся Q правильных P-угольников.
+type Hyperbolic struct {
+	P int
+	Q int
+}
+
+// mobius - дробно-линейное преобразование единичного круга z' = (a*z + b) / (c*z + d).
+type mobius struct {
+	a, b, c, d complex128
+}
+
+func (m mobius) transform(_ *rand.Rand, x, y float64) (newX, newY float64) {
+	z := complex(x, y)
+	w := (m.a*z + m.b) / (m.c*z + m.d)
+
+	return real(w), imag(w)
+}
+
+// halfTurnAround - поворот на π вокруг точки center вещественной оси внутри круга Пуанкаре.
+func halfTurnAround(center float64) mobius {
+	c := complex(center, 0)
+
+	return mobius{a: 1 + c*c, b: -2 * c, c: 2 * c, d: -(1 + c*c)}
+}
+
+// SetHyperbolicTiling - включает режим симметрии гиперболической мозаики {p, q} в круге Пуанкаре. В игру хаоса
+// добавляются повороты на 2πk/p вокруг центра круга и поворот на π вокруг середины стороны центрального
+// многоугольника (преобразование Мебиуса), которые вместе порождают группу вращений мозаики. Точки вне
+// единичного круга не отрисовываются. Мозаика существует только при (p-2)(q-2) > 4.
+func (im *ImageMatrix) SetHyperbolicTiling(p, q int) error {
+	if p < 3 || q < 3 || (p-2)*(q-2) <= 4 {
+		return errors.ErrHyperbolicTiling{P: p, Q: q}
+	}
+
+	for k := 1; k < p; k++ {
+		angle := 2 * math.Pi * float64(k) / float64(p)
+		cos, sin := math.Cos(angle), math.Sin(angle)
+
+		im.LinearTransformations = append(im.LinearTransformations, symmetryTransformation(cos, -sin, sin, cos))
+	}
+
+	// Середина стороны лежит на гиперболическом расстоянии d от центра, cosh d = cos(π/q) / sin(π/p),
+	// в круге Пуанкаре этому соответствует евклидов радиус tanh(d/2).
+	distance := math.Acosh(math.Cos(math.Pi/float64(q)) / math.Sin(math.Pi/float64(p)))
+	turn := halfTurnAround(math.Tanh(distance / 2))
+
+	im.LinearTransformations = append(im.LinearTransformations, AffineTransformation{
+		A:          1,
+		D:          1,
+		Variations: []Variation{{Name: "Mobius", Weight: 1, Func: turn.transform}},
+		Symmetry:   true,
+	})
+
+	im.hyperbolic = &Hyperbolic{P: p, Q: q}
+
+	return nil
+}
+
+// HyperbolicTiling - возвращает параметры гиперболической мозаики или nil, если режим выключен.
+func (im *ImageMatrix) HyperbolicTiling() *Hyperbolic {
+	return im.hyperbolic
+}
+
+// outsideDisk - маска границы круга Пуанкаре: в гиперболическом режиме точки вне единичного круга отбрасываются.
+func (im *ImageMatrix) outsideDisk(x, y float64) bool {
+	return im.hyperbolic != nil && x*x+y*y >= 1
+}
@@ -0,0 +1,74 @@
+package domain_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+)
+
+// applyXform - применяет аффинную часть и основную смесь преобразования без случайного выбора.
+func applyXform(tr *domain.AffineTransformation, x, y float64) (newX, newY float64) {
+	x, y = tr.A*x+tr.B*y+tr.C, tr.D*y+tr.E*x-tr.F
+
+	for _, v := range tr.Variations {
+		vx, vy := v.Func(nil, x, y)
+		newX, newY = newX+v.Weight*vx, newY+v.Weight*vy
+	}
+
+	return newX, newY
+}
+
+// Поворот вокруг центра на 2π/p, за которым следует поворот на π вокруг середины стороны, - это поворот вокруг
+// вершины мозаики, поэтому q таких шагов возвращают точку на место.
+func TestSetHyperbolicTiling_VertexRotationHasOrderQ(t *testing.T) {
+	for _, tiling := range [][2]int{{7, 3}, {5, 4}, {4, 5}, {6, 6}, {3, 8}} {
+		p, q := tiling[0], tiling[1]
+
+		im := domain.NewImageMatrix(32, 32, 1, 1)
+		before := len(im.LinearTransformations)
+
+		if err := im.SetHyperbolicTiling(p, q); err != nil {
+			t.Fatalf("{%d,%d}: %v", p, q, err)
+		}
+
+		added := im.LinearTransformations[before:]
+		if len(added) != p {
+			t.Fatalf("{%d,%d}: got %d symmetry xforms, want %d", p, q, len(added), p)
+		}
+
+		rotation, turn := &added[0], &added[len(added)-1]
+		startX, startY := 0.13, -0.21
+		x, y := startX, startY
+
+		for step := 0; step < q; step++ {
+			x, y = applyXform(turn, x, y)
+			x, y = applyXform(rotation, x, y)
+
+			if x*x+y*y >= 1 {
+				t.Fatalf("{%d,%d}: point left the disk at step %d", p, q, step)
+			}
+
+			if step < q-1 && math.Hypot(x-startX, y-startY) < 1e-9 {
+				t.Fatalf("{%d,%d}: point returned after %d steps", p, q, step+1)
+			}
+		}
+
+		if d := math.Hypot(x-startX, y-startY); d > 1e-9 {
+			t.Errorf("{%d,%d}: point moved by %g after %d vertex rotations", p, q, d, q)
+		}
+	}
+}
+
+func TestSetHyperbolicTiling_RejectsEuclideanAndSphericalTilings(t *testing.T) {
+	for _, tiling := range [][2]int{{4, 4}, {6, 3}, {3, 6}, {5, 3}, {2, 7}} {
+		im := domain.NewImageMatrix(32, 32, 1, 1)
+
+		err := im.SetHyperbolicTiling(tiling[0], tiling[1])
+		if !errors.As(err, &domainErrors.ErrHyperbolicTiling{}) {
+			t.Errorf("{%d,%d}: got %v, want ErrHyperbolicTiling", tiling[0], tiling[1], err)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package domain
 
 import (
-	"image"
 	"image/color"
 	"math"
 	"math/rand/v2"
@@ -10,12 +9,37 @@ import (
 	"FractalFlame/pkg/random"
 )
 
+// AffineTransformation - одно преобразование пламени (xform): аффинная часть, цвет и упорядоченные списки
+// нелинейных преобразований, которые применяются до основной смеси, в ней и после нее.
 type AffineTransformation struct {
 	A, B, C, D, E, F     float64
 	TransformationColour color.RGBA
+	PreVariations        []Variation
+	Variations           []Variation
+	PostVariations       []Variation
+	Post                 *PostAffine
+	Z                    *ZAffine
+	Symmetry             bool
 }
 
-type TransformFunc func(x, y float64) (newX, newY float64)
+// ZAffine - необязательное аффинное преобразование координаты z: z' = A*x + B*y + C*z + D. Отсутствие
+// означает, что z не меняется.
+type ZAffine struct {
+	A, B, C, D float64
+}
+
+// PostAffine - необязательное аффинное преобразование, которое применяется после нелинейных преобразований,
+// коэффициенты используются по той же формуле, что и в основной аффинной части. Отсутствие означает
+// тождественное преобразование.
+type PostAffine struct {
+	A, B, C, D, E, F float64
+}
+
+// IdentityPostAffine - тождественное пост-аффинное преобразование.
+var IdentityPostAffine = PostAffine{A: 1, D: 1}
+
+// TransformFunc - двумерное нелинейное преобразование, случайные числа берутся из генератора рабочего потока.
+type TransformFunc func(rng *rand.Rand, x, y float64) (newX, newY float64)
 
 type ImageMatrix struct {
 	Resolution               *Resolution
@@ -25,14 +49,31 @@ type ImageMatrix struct {
 	Pixels                   [][]Pixel
 	LinearTransformations    []AffineTransformation
 	NonLinearTransformations []TransformFunc
+	Camera3D                 *Camera3D
+	Seed                     uint64
+	Symmetry                 int
+	Tileable                 bool
+	Background               *Background
+	PremultipliedAlpha       bool
+	BitDepth                 int
+	camera                   Camera
+	view                     view
+	wallpaper                *wallpaper
+	hyperbolic               *Hyperbolic
+	projection               Projection
+	stereo                   *Stereo
+	band                     *band
+	scratch                  *filterScratch
 }
 
+// Pixel - накопленные данные пикселя: число попаданий и суммы цветов преобразований, которые в него попали.
+// Colour заполняется при тональной компрессии (см. ToneMap).
 type Pixel struct {
-	X, Y    int
-	HitRate int
-	Colour  color.RGBA
-	normal  float64
-	mutex   sync.Mutex
+	X, Y      int
+	HitRate   int
+	Colour    FloatColour
+	colourSum [3]float64
+	mutex     sync.Mutex
 }
 
 type Resolution struct {
@@ -46,7 +87,18 @@ type CoordinatesRange struct {
 
 const amountOfAffine = 7
 
+// Потоки генератора для случайного генома, номера стартовых точек используют потоки начиная с нуля.
+const (
+	affineStream     = 1 << 63
+	postAffineStream = affineStream + 1
+)
+
 func NewImageMatrix(width, height, startingPoints, iterations int) *ImageMatrix {
+	return newImageMatrix(width, height, height, startingPoints, iterations)
+}
+
+// newImageMatrix - буфер изображения width x height, в котором выделено rows строк пикселей.
+func newImageMatrix(width, height, rows, startingPoints, iterations int) *ImageMatrix {
 	resolution := Resolution{
 		Width:  width,
 		Height: height,
@@ -54,11 +106,11 @@ func NewImageMatrix(width, height, startingPoints, iterations int) *ImageMatrix
 
 	NonlinearTransformations := make([]TransformFunc, 0, 10)
 
-	matrix := make([][]Pixel, resolution.Height)
-	for y := 0; y < resolution.Height; y++ {
+	matrix := make([][]Pixel, rows)
+	for y := 0; y < rows; y++ {
 		matrix[y] = make([]Pixel, resolution.Width)
 		for x := 0; x < resolution.Width; x++ {
-			matrix[y][x] = Pixel{X: x, Y: y, Colour: color.RGBA{R: 0, G: 0, B: 0, A: 255}}
+			matrix[y][x] = Pixel{X: x, Y: y}
 		}
 	}
 
@@ -81,45 +133,71 @@ func NewImageMatrix(width, height, startingPoints, iterations int) *ImageMatrix
 
 	Affine := make([]AffineTransformation, amountOfAffine)
 
-	return &ImageMatrix{Pixels: matrix, Resolution: &resolution, LinearTransformations: Affine,
+	im := &ImageMatrix{Pixels: matrix, Resolution: &resolution, LinearTransformations: Affine,
 		NonLinearTransformations: NonlinearTransformations, StartingPoints: startingPoints, Iterations: iterations, cords: cords}
+	im.SetCamera(Camera{})
+
+	return im
+}
+
+// SetCamera - задает камеру, по которой точки отображаются в пиксели. Нулевой масштаб означает масштаб
+// по умолчанию, при котором видна область [-k; k]x[-1; 1] с учетом соотношения сторон.
+func (im *ImageMatrix) SetCamera(c Camera) {
+	width, height := im.frame()
+
+	if c.Scale == 0 {
+		c.Scale = DefaultScale(width, height)
+	}
+
+	im.camera = c
+	im.view = newView(c, width, height)
+
+	if im.wallpaper != nil {
+		im.wallpaper.updateCells(&im.view, width, height)
+	}
+}
+
+// Camera - возвращает текущую камеру.
+func (im *ImageMatrix) Camera() Camera {
+	return im.camera
 }
 
 // GetNonLinearTransform - возвращает применение к координатам случайной функции нелинейного преобразования.
-func (im *ImageMatrix) GetNonLinearTransform(x, y float64) (newX, newY float64) {
-	k := rand.IntN(len(im.NonLinearTransformations)) //nolint
-	return im.NonLinearTransformations[k](x, y)
+func (im *ImageMatrix) GetNonLinearTransform(rng *rand.Rand, x, y float64) (newX, newY float64) {
+	k := rng.IntN(len(im.NonLinearTransformations))
+	return im.NonLinearTransformations[k](rng, x, y)
 }
 
 // GenerateAffineTransformations - функция, которая генерирует все 7(определенно константой) случайных аффинных
-// преобразований.
+// преобразований, генерация детерминирована при заданном Seed.
 func (im *ImageMatrix) GenerateAffineTransformations() {
+	rng := random.New(im.Seed, affineStream)
+
 	for i := 0; i < amountOfAffine; i++ {
-		im.LinearTransformations[i] = im.generateCoefficients()
+		im.LinearTransformations[i] = im.generateCoefficients(rng)
 	}
 }
 
-// GetAffineTransform - позволяет получить одно случайное
-// из 7(определенно константой) линейных(аффинных) преобразований.
-func (im *ImageMatrix) GetAffineTransform() AffineTransformation {
-	x := rand.IntN(amountOfAffine) //nolint
-	return im.LinearTransformations[x]
+// GetAffineTransform - позволяет получить одно случайное из линейных(аффинных) преобразований.
+func (im *ImageMatrix) GetAffineTransform(rng *rand.Rand) *AffineTransformation {
+	x := rng.IntN(len(im.LinearTransformations))
+	return &im.LinearTransformations[x]
 }
 
 // generateCoefficients -позволяет сгенерировать коэффициенты и цвет для линейного преобразования.
-func (im *ImageMatrix) generateCoefficients() AffineTransformation {
+func (im *ImageMatrix) generateCoefficients(rng *rand.Rand) AffineTransformation {
 	for {
-		a := random.GenerateRandFloat64()
-		b := random.GenerateRandFloat64()
-		d := random.GenerateRandFloat64()
-		e := random.GenerateRandFloat64()
+		a := random.GenerateRandFloat64(rng)
+		b := random.GenerateRandFloat64(rng)
+		d := random.GenerateRandFloat64(rng)
+		e := random.GenerateRandFloat64(rng)
 
 		if math.Pow(a, 2)+math.Pow(d, 2) < 1 &&
 			math.Pow(b, 2)+math.Pow(e, 2) < 1 &&
 			math.Pow(a, 2)+math.Pow(b, 2)+math.Pow(d, 2)+math.Pow(e, 2) < 1+math.Pow(a*e-b*d, 2) {
-			c := random.GenerateRandFloat64()
-			f := random.GenerateRandFloat64()
-			colour := random.GenerateRandomColor()
+			c := random.GenerateRandFloat64(rng)
+			f := random.GenerateRandFloat64(rng)
+			colour := random.GenerateRandomColor(rng)
 
 			return AffineTransformation{
 				A:                    a,
@@ -134,106 +212,92 @@ func (im *ImageMatrix) generateCoefficients() AffineTransformation {
 	}
 }
 
-// averageColor - позволяет усреднить два цвета, и вернуть результат усреднения.
-func (im *ImageMatrix) averageColor(c1, c2 color.RGBA) color.RGBA {
-	var r, g, b uint8
-
-	red, green, blue, _ := c1.RGBA()
-	redNew, greenNew, blueNew, _ := c2.RGBA()
+// GeneratePostAffineTransformations - добавляет каждому преобразованию случайное пост-аффинное преобразование,
+// которое поворачивает и масштабирует результат нелинейных преобразований.
+func (im *ImageMatrix) GeneratePostAffineTransformations() {
+	rng := random.New(im.Seed, postAffineStream)
 
-	r, g, b = byte((red>>8+redNew>>8)>>1), byte((green>>8+greenNew>>8)>>1), byte((blue>>8+blueNew>>8)>>1)
-
-	return color.RGBA{
-		R: r,
-		G: g,
-		B: b,
-		A: 255,
-	}
-}
+	for i := range im.LinearTransformations {
+		angle := math.Pi * random.GenerateRandFloat64(rng)
+		scale := 1 + 0.5*random.GenerateRandFloat64(rng)
 
-// Correction - реализация алгоритма гамма коррекции.
-func (im *ImageMatrix) Correction(gamma float64) {
-	var maxNormalizedHitRate float64
-
-	for row := range im.Pixels {
-		for col := range im.Pixels[row] {
-			if im.Pixels[row][col].HitRate != 0 {
-				im.Pixels[row][col].normal = math.Log10(float64(im.Pixels[row][col].HitRate))
-				if im.Pixels[row][col].normal > maxNormalizedHitRate {
-					maxNormalizedHitRate = im.Pixels[row][col].normal
-				}
-			}
+		im.LinearTransformations[i].Post = &PostAffine{
+			A: scale * math.Cos(angle),
+			B: -scale * math.Sin(angle),
+			D: scale * math.Cos(angle),
+			E: scale * math.Sin(angle),
 		}
 	}
+}
 
-	for row := range im.Pixels {
-		for col := range im.Pixels[row] {
-			adjusted := math.Pow(im.Pixels[row][col].normal, 1.0/gamma)
+// Apply - применяет аффинное преобразование к координате z.
+func (za *ZAffine) Apply(x, y, z float64) float64 {
+	return za.A*x + za.B*y + za.C*z + za.D
+}
 
-			im.Pixels[row][col].Colour.R = uint8(float64(im.Pixels[row][col].Colour.R) * adjusted)
-			im.Pixels[row][col].Colour.G = uint8(float64(im.Pixels[row][col].Colour.G) * adjusted)
-			im.Pixels[row][col].Colour.B = uint8(float64(im.Pixels[row][col].Colour.B) * adjusted)
-		}
-	}
+// Apply - применяет пост-аффинное преобразование к точке.
+func (p *PostAffine) Apply(x, y float64) (newX, newY float64) {
+	return p.A*x + p.B*y + p.C, p.D*y + p.E*x - p.F
 }
 
-// ReflectHorizontally - реализация симметрии по иксу.
+// ReflectHorizontally - зеркальное отражение левой половины изображения на правую, применяется как
+// постобработка.
 func (im *ImageMatrix) ReflectHorizontally() {
-	for y := 0; y < len(im.Pixels); y++ {
-		for x := 0; x < len(im.Pixels[y])/2; x++ {
-			mirrorX := len(im.Pixels[y]) - 1 - x
-			im.Pixels[y][mirrorX] = Pixel{
-				Colour:  im.Pixels[y][x].Colour,
-				X:       x,
-				Y:       y,
-				HitRate: im.Pixels[y][x].HitRate,
+	width, height := im.frame()
+
+	for _, offset := range im.frameOffsets() {
+		for y := offset[1]; y < offset[1]+height; y++ {
+			for x := 0; x < width/2; x++ {
+				mirrorX := offset[0] + width - 1 - x
+				im.Pixels[y][mirrorX].copyFrom(&im.Pixels[y][offset[0]+x])
 			}
 		}
 	}
 }
 
-// ReflectVertically - реализация симметрии по игреку.
+// ReflectVertically - зеркальное отражение верхней половины изображения на нижнюю, применяется как
+// постобработка.
 func (im *ImageMatrix) ReflectVertically() {
-	for y := 0; y < len(im.Pixels)/2; y++ {
-		mirrorY := len(im.Pixels) - 1 - y
-
-		copy(im.Pixels[mirrorY], im.Pixels[y])
-	}
-}
+	width, height := im.frame()
 
-// ConvertToImage - преобразовывает структуру ImageMatrix в картинку.
-func (im *ImageMatrix) ConvertToImage() image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, im.Resolution.Width, im.Resolution.Height))
+	for _, offset := range im.frameOffsets() {
+		for y := 0; y < height/2; y++ {
+			mirrorY := offset[1] + height - 1 - y
 
-	for y, row := range im.Pixels {
-		for x := range row {
-			img.Set(x, y, row[x].Colour)
+			for x := offset[0]; x < offset[0]+width; x++ {
+				im.Pixels[mirrorY][x].copyFrom(&im.Pixels[offset[1]+y][x])
+			}
 		}
 	}
-
-	return img
 }
 
-// UpdatePixel - отвечает за обработку одного пикселя в рамках работы алгоритма.
-func (im *ImageMatrix) UpdatePixel(pixelY, pixelX int, linearCoeffs AffineTransformation) {
-	im.Pixels[pixelY][pixelX].mutex.Lock()
-	defer im.Pixels[pixelY][pixelX].mutex.Unlock()
+// copyFrom - копирует накопленные данные пикселя, сохраняя его собственные координаты.
+func (p *Pixel) copyFrom(src *Pixel) {
+	p.HitRate = src.HitRate
+	p.Colour = src.Colour
+	p.colourSum = src.colourSum
+}
 
-	if im.Pixels[pixelY][pixelX].HitRate == 0 {
-		im.Pixels[pixelY][pixelX].Colour = linearCoeffs.TransformationColour
-		im.Pixels[pixelY][pixelX].HitRate++
+// UpdatePixel - отвечает за обработку одного пикселя в рамках работы алгоритма: увеличивает число попаданий
+// и добавляет цвет преобразования к сумме. Компоненты цвета целые, поэтому суммы не зависят от порядка
+// попаданий, и многопоточный рендер дает тот же результат, что и однопоточный.
+func (im *ImageMatrix) UpdatePixel(pixelY, pixelX int, linearCoeffs *AffineTransformation) {
+	pixel := &im.Pixels[pixelY][pixelX]
+	colour := linearCoeffs.TransformationColour
 
-		return
-	}
+	pixel.mutex.Lock()
+	defer pixel.mutex.Unlock()
 
-	im.Pixels[pixelY][pixelX].Colour = im.averageColor(im.Pixels[pixelY][pixelX].Colour, linearCoeffs.TransformationColour)
-	im.Pixels[pixelY][pixelX].HitRate++
+	pixel.colourSum[0] += float64(colour.R)
+	pixel.colourSum[1] += float64(colour.G)
+	pixel.colourSum[2] += float64(colour.B)
+	pixel.HitRate++
 }
 
 // GenerateStartingCoordinates - позволяет получить координаты стартовых точек для работы алгоритма.
-func (im *ImageMatrix) GenerateStartingCoordinates() (newX, newY float64) {
-	newX = random.GenerateRandFloat64() //nolint
-	newY = random.GenerateRandFloat64() //nolint
+func (im *ImageMatrix) GenerateStartingCoordinates(rng *rand.Rand) (newX, newY float64) {
+	newX = random.GenerateRandFloat64(rng)
+	newY = random.GenerateRandFloat64(rng)
 
 	newX = newX*(im.cords.xMax-im.cords.xMin) + im.cords.xMin
 	newY = newY*(im.cords.yMax-im.cords.yMin) + im.cords.yMin
@@ -242,26 +306,139 @@ func (im *ImageMatrix) GenerateStartingCoordinates() (newX, newY float64) {
 }
 
 // ProcessStartingPoint - функция реализующая логику обработки каждой стартовой точки, вынесено в отдельную во избежание
-// дублирования кода.
-func (im *ImageMatrix) ProcessStartingPoint() {
-	newX, newY := im.GenerateStartingCoordinates()
+// дублирования кода. Генератор рабочего потока переинициализируется от Seed и номера точки index.
+func (im *ImageMatrix) ProcessStartingPoint(index int, workerRand *random.WorkerRand) {
+	im.iterate(index, im.Iterations, workerRand, im.plot)
+}
+
+// iterate - выполняет iterations шагов алгоритма для стартовой точки index и передает каждую полученную
+// точку в visit вместе с преобразованием, которое задает ее цвет. Отрисовывается результат всего
+// преобразования: аффинной части, нелинейных преобразований и пост-аффинного, как во flam3.
+func (im *ImageMatrix) iterate(index, iterations int, workerRand *random.WorkerRand,
+	visit func(rng *rand.Rand, x, y, z float64, linearCoeffs *AffineTransformation)) {
+	var (
+		newZ   float64
+		colour *AffineTransformation
+	)
+
+	workerRand.Reseed(im.Seed, uint64(index))
+	rng := workerRand.Rand
 
-	for step := -20; step < im.Iterations; step++ {
-		linearCoeffs := im.GetAffineTransform() // Получаем линейные коэффициенты трансформации
+	newX, newY := im.GenerateStartingCoordinates(rng)
+
+	for step := -20; step < iterations; step++ {
+		linearCoeffs := im.GetAffineTransform(rng) // Получаем линейные коэффициенты трансформации
 		x := linearCoeffs.A*newX + linearCoeffs.B*newY + linearCoeffs.C
 		y := linearCoeffs.D*newY + linearCoeffs.E*newX - linearCoeffs.F
+		z := newZ
 
-		if step >= 0 {
-			pixelX := im.Resolution.Width - int(math.Trunc(((im.cords.xMax-x)/(im.cords.xMax-im.cords.xMin))*
-				float64(im.Resolution.Width)))
-			pixelY := im.Resolution.Height - int(math.Trunc(((im.cords.yMax-y)/(im.cords.yMax-im.cords.yMin))*
-				float64(im.Resolution.Height)))
+		if linearCoeffs.Z != nil {
+			z = linearCoeffs.Z.Apply(newX, newY, newZ)
+		}
 
-			if pixelX >= 0 && pixelY >= 0 && pixelY < im.Resolution.Height && pixelX < im.Resolution.Width {
-				im.UpdatePixel(pixelY, pixelX, linearCoeffs)
-			}
+		newX, newY, newZ = im.applyVariations(rng, linearCoeffs, x, y, z)
+
+		if linearCoeffs.Post != nil {
+			newX, newY = linearCoeffs.Post.Apply(newX, newY)
 		}
 
-		newX, newY = im.GetNonLinearTransform(x, y)
+		if im.wallpaper != nil {
+			newX, newY = im.wallpaper.fold(newX, newY)
+		}
+
+		// Преобразования симметрии не меняют цвет точки. Пока не применено ни одно обычное преобразование,
+		// у точки нет цвета, и она не отрисовывается.
+		if !linearCoeffs.Symmetry {
+			colour = linearCoeffs
+		}
+
+		if step >= 0 && colour != nil {
+			visit(rng, newX, newY, newZ, colour)
+		}
+	}
+}
+
+// project - переводит точку пространства в точку плоскости изображения с учетом 3D камеры.
+func (im *ImageMatrix) project(rng *rand.Rand, x, y, z float64) (projX, projY float64, ok bool) {
+	if im.Camera3D == nil {
+		return x, y, true
+	}
+
+	return im.Camera3D.Project(rng, x, y, z)
+}
+
+// plot - проецирует точку на изображение и обновляет попавший в нее пиксель.
+func (im *ImageMatrix) plot(rng *rand.Rand, x, y, z float64, linearCoeffs *AffineTransformation) {
+	if im.wallpaper != nil {
+		x, y = im.wallpaper.place(rng, x, y)
+	}
+
+	if im.outsideDisk(x, y) {
+		return
+	}
+
+	if im.stereo != nil {
+		im.plotStereo(rng, x, y, z, linearCoeffs)
+
+		return
+	}
+
+	fx, fy, ok := im.toPixel(rng, x, y, z)
+	if !ok {
+		return
+	}
+
+	if im.band != nil {
+		fy -= float64(im.band.top)
 	}
+
+	im.plotPixel(fx, fy, [2]int{}, linearCoeffs)
+}
+
+// plotPixel - обновляет пиксель кадра с левым верхним углом offset, в который попадают дробные координаты.
+func (im *ImageMatrix) plotPixel(fx, fy float64, offset [2]int, linearCoeffs *AffineTransformation) {
+	width, height := im.frame()
+
+	if im.Tileable {
+		fx, fy = wrap(fx, float64(width)), wrap(fy, float64(height))
+	}
+
+	pixelX, pixelY := int(math.Floor(fx)), int(math.Floor(fy))
+
+	if pixelX >= 0 && pixelY >= 0 && pixelY < height && pixelX < width {
+		im.UpdatePixel(offset[1]+pixelY, offset[0]+pixelX, linearCoeffs)
+	}
+}
+
+// toPixel - переводит точку пламени в дробные координаты пикселя с учетом камеры и проекции.
+func (im *ImageMatrix) toPixel(rng *rand.Rand, x, y, z float64) (pixelX, pixelY float64, ok bool) {
+	if im.projection != ProjectionPlane {
+		dx, dy, dz, ok := im.direction(x, y, z)
+		if !ok {
+			return 0, 0, false
+		}
+
+		pixelX, pixelY = im.sphereToPixel(dx, dy, dz)
+
+		return pixelX, pixelY, true
+	}
+
+	x, y, ok = im.project(rng, x, y, z)
+	if !ok {
+		return 0, 0, false
+	}
+
+	pixelX, pixelY = im.view.toPixel(x, y)
+
+	return pixelX, pixelY, true
+}
+
+// wrap - приводит координату к отрезку [0; size) для бесшовного (тороидального) режима.
+func wrap(value, size float64) float64 {
+	value = math.Mod(value, size)
+	if value < 0 {
+		value += size
+	}
+
+	return value
 }
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"image"
+	"math"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// Projection - способ отображения точек пламени в изображение.
+type Projection int
+
+const (
+	// ProjectionPlane - обычная плоская камера.
+	ProjectionPlane Projection = iota
+	// ProjectionEquirectangular - панорама 360°: точки переносятся на сферу и записываются в изображение 2:1
+	// по долготе и широте.
+	ProjectionEquirectangular
+	// ProjectionCubemap - та же сфера, записанная в шесть граней куба.
+	ProjectionCubemap
+)
+
+// CubemapFaces - имена граней кубической карты в порядке их расположения в буфере, оси соответствуют
+// соглашению OpenGL (ось +y направлена в зенит).
+var CubemapFaces = [6]string{"posx", "negx", "posy", "negy", "posz", "negz"}
+
+// ParseProjection - разбирает название проекции из конфигурации, пустая строка означает плоскую камеру.
+func ParseProjection(name string) (Projection, error) {
+	switch name {
+	case "", "plane":
+		return ProjectionPlane, nil
+	case "equirectangular":
+		return ProjectionEquirectangular, nil
+	case "cubemap":
+		return ProjectionCubemap, nil
+	}
+
+	return ProjectionPlane, errors.ErrUnknownProjection{Name: name}
+}
+
+func (p Projection) String() string {
+	switch p {
+	case ProjectionEquirectangular:
+		return "equirectangular"
+	case ProjectionCubemap:
+		return "cubemap"
+	default:
+		return "plane"
+	}
+}
+
+// ProjectionResolution - размер буфера для проекции: панорама всегда имеет соотношение сторон 2:1 и высоту
+// width/2, кубическая карта хранит шесть квадратных граней со стороной height в одну строку.
+func ProjectionResolution(p Projection, width, height int) (bufferWidth, bufferHeight int) {
+	switch p {
+	case ProjectionEquirectangular:
+		return width, width / 2
+	case ProjectionCubemap:
+		return 6 * height, height
+	default:
+		return width, height
+	}
+}
+
+// SetProjection - задает проекцию, размер изображения должен быть получен через ProjectionResolution.
+func (im *ImageMatrix) SetProjection(p Projection) {
+	im.projection = p
+}
+
+// Projection - возвращает текущую проекцию.
+func (im *ImageMatrix) Projection() Projection {
+	return im.projection
+}
+
+// direction - переводит точку пламени в направление на сфере. Трехмерные точки поворачиваются камерой
+// Camera3D, а плоскость двумерного пламени после камеры переносится на сферу обратной стереографической
+// проекцией: центр кадра попадает в зенит, единичная окружность - на горизонт.
+func (im *ImageMatrix) direction(x, y, z float64) (dx, dy, dz float64, ok bool) {
+	if im.Camera3D != nil {
+		dx, dy, dz = im.Camera3D.rotate(x, y, z)
+
+		return dx, dy, dz, dx != 0 || dy != 0 || dz != 0
+	}
+
+	u, v := im.view.toPlane(x, y)
+	r2 := u*u + v*v
+
+	return 2 * u / (1 + r2), 2 * v / (1 + r2), (1 - r2) / (1 + r2), true
+}
+
+// sphereToPixel - отображает направление в дробные координаты пикселя панорамы или кубической карты.
+func (im *ImageMatrix) sphereToPixel(dx, dy, dz float64) (pixelX, pixelY float64) {
+	width, height := float64(im.Resolution.Width), float64(im.Resolution.Height)
+
+	if im.projection == ProjectionCubemap {
+		face, s, t := cubemapFace(dx, dz, -dy)
+
+		return (float64(face) + s) * height, t * height
+	}
+
+	longitude := math.Atan2(dy, dx)
+	latitude := math.Asin(math.Max(-1, math.Min(1, dz/math.Sqrt(dx*dx+dy*dy+dz*dz))))
+
+	pixelX = wrap((longitude+math.Pi)/(2*math.Pi)*width, width)
+	pixelY = math.Min((math.Pi/2-latitude)/math.Pi*height, math.Nextafter(height, 0))
+
+	return pixelX, pixelY
+}
+
+// cubemapFace - выбирает грань куба по наибольшей компоненте направления (оси OpenGL) и возвращает
+// координаты s, t на грани из [0; 1].
+func cubemapFace(rx, ry, rz float64) (face int, s, t float64) {
+	ax, ay, az := math.Abs(rx), math.Abs(ry), math.Abs(rz)
+
+	var sc, tc, ma float64
+
+	switch {
+	case ax >= ay && ax >= az && rx > 0:
+		face, sc, tc, ma = 0, -rz, -ry, ax
+	case ax >= ay && ax >= az:
+		face, sc, tc, ma = 1, rz, -ry, ax
+	case ay >= az && ry > 0:
+		face, sc, tc, ma = 2, rx, rz, ay
+	case ay >= az:
+		face, sc, tc, ma = 3, rx, -rz, ay
+	case rz > 0:
+		face, sc, tc, ma = 4, rx, -ry, az
+	default:
+		face, sc, tc, ma = 5, -rx, -ry, az
+	}
+
+	s = math.Min((sc/ma+1)/2, math.Nextafter(1, 0))
+	t = math.Min((tc/ma+1)/2, math.Nextafter(1, 0))
+
+	return face, s, t
+}
+
+// ConvertCubemap - переводит буфер кубической карты в шесть изображений граней в порядке CubemapFaces.
+func (im *ImageMatrix) ConvertCubemap() []image.Image {
+	size := im.Resolution.Height
+	faces := make([]image.Image, len(CubemapFaces))
+
+	for face := range faces {
+		img, set := im.outputImage(nil, size, size)
+
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				set(x, y, im.outputColour(im.Pixels[y][face*size+x].Colour, y))
+			}
+		}
+
+		faces[face] = img
+	}
+
+	return faces
+}
@@ -0,0 +1,112 @@
+package domain_test
+
+import (
+	"testing"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+func renderProjection(p domain.Projection, width, height int) *domain.ImageMatrix {
+	width, height = domain.ProjectionResolution(p, width, height)
+
+	im := domain.NewImageMatrix(width, height, 16, 20000)
+	im.Seed = 42
+	im.SetProjection(p)
+	im.SetCamera(domain.Camera{Zoom: -1})
+	im.GenerateAffineTransformations()
+	im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Linear,
+		transformations.Disc, transformations.Sinusoidal)
+
+	rng := random.NewWorkerRand()
+	for i := 0; i < im.StartingPoints; i++ {
+		im.ProcessStartingPoint(i, rng)
+	}
+
+	im.ToneMap(0)
+	im.Filter(2)
+
+	return im
+}
+
+func TestEquirectangular_IsTwoToOneAndWrapsHorizontally(t *testing.T) {
+	im := renderProjection(domain.ProjectionEquirectangular, 128, 0)
+	width := im.Resolution.Width
+
+	if im.Resolution.Height*2 != width {
+		t.Fatalf("panorama is %dx%d, want 2:1", width, im.Resolution.Height)
+	}
+
+	var interior float64
+
+	for x := 0; x < width-1; x++ {
+		interior += columnDifference(im, x, x+1)
+	}
+
+	interior /= float64(width - 1)
+
+	seam := columnDifference(im, width-1, 0)
+	if seam > 2*interior {
+		t.Errorf("seam at longitude ±180° %.2f, mean difference between neighbouring columns %.2f", seam, interior)
+	}
+}
+
+// rowSpread - число закрашенных пикселей в строке.
+func rowSpread(im *domain.ImageMatrix, row int) int {
+	var lit int
+
+	for x := range im.Pixels[row] {
+		if luminance(im.Pixels[row][x].Colour) > 0 {
+			lit++
+		}
+	}
+
+	return lit
+}
+
+func TestEquirectangular_FilterWidensTowardsPoles(t *testing.T) {
+	im := domain.NewImageMatrix(128, 64, 1, 1)
+	im.SetProjection(domain.ProjectionEquirectangular)
+
+	white := domain.FloatColour{1, 1, 1, 1}
+	im.Pixels[32][64].Colour = white
+	im.Pixels[3][64].Colour = white
+
+	im.Filter(2)
+
+	equator, polar := rowSpread(im, 32), rowSpread(im, 3)
+	if polar <= 2*equator {
+		t.Errorf("filter footprint near the pole %d pixels, at the equator %d pixels", polar, equator)
+	}
+}
+
+func TestCubemap_EveryFaceIsRendered(t *testing.T) {
+	im := renderProjection(domain.ProjectionCubemap, 0, 32)
+
+	faces := im.ConvertCubemap()
+	if len(faces) != len(domain.CubemapFaces) {
+		t.Fatalf("got %d faces, want %d", len(faces), len(domain.CubemapFaces))
+	}
+
+	for i, face := range faces {
+		if b := face.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+			t.Errorf("face %s is %dx%d, want 32x32", domain.CubemapFaces[i], b.Dx(), b.Dy())
+		}
+	}
+
+	// Центр кадра переносится в зенит, поэтому верхняя грань должна быть закрашена.
+	var lit int
+
+	for y := 0; y < 32; y++ {
+		for x := 2 * 32; x < 3*32; x++ {
+			if im.Pixels[y][x].HitRate > 0 {
+				lit++
+			}
+		}
+	}
+
+	if lit == 0 {
+		t.Errorf("face %s received no points", domain.CubemapFaces[2])
+	}
+}
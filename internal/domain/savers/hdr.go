@@ -0,0 +1,144 @@
+package savers
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/errors"
+)
+
+// HdrSaver - сохраняет линейные данные изображения в Radiance RGBE (.hdr): общая экспонента на пиксель,
+// строки без сжатия.
+type HdrSaver struct{}
+
+// Save - сохраняет изображение в файл name.hdr, метаданные записываются строками заголовка.
+func (h *HdrSaver) Save(img image.Image, name string, meta Metadata) error {
+	file, err := os.Create(name + ".hdr")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return EncodeHdr(file, img, meta)
+}
+
+// EncodeHdr - записывает изображение в формате Radiance RGBE. Компоненты FloatImage пишутся без изменений,
+// обычное изображение переводится в [0; 1].
+func EncodeHdr(w io.Writer, img image.Image, meta Metadata) error {
+	bounds := img.Bounds()
+	out := bufio.NewWriter(w)
+
+	header := "#?RADIANCE\n"
+	for _, key := range meta.keys() {
+		header += key + "=" + strings.ReplaceAll(meta[key], "\n", " ") + "\n"
+	}
+
+	header += fmt.Sprintf("FORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", bounds.Dy(), bounds.Dx())
+
+	if _, err := out.WriteString(header); err != nil {
+		return err
+	}
+
+	row := make([]byte, 4*bounds.Dx())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			rgbe := toRGBE(linearAt(img, x, y))
+			copy(row[4*(x-bounds.Min.X):], rgbe[:])
+		}
+
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// DecodeHdr - читает файл Radiance RGBE со строками без сжатия и ориентацией -Y +X, как их пишет EncodeHdr.
+func DecodeHdr(r io.Reader) (*domain.FloatImage, error) {
+	in := bufio.NewReader(r)
+
+	line, err := in.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "#?") {
+		return nil, errors.ErrDecoding{Format: "HDR", Reason: "missing #? signature"}
+	}
+
+	for {
+		if line, err = in.ReadString('\n'); err != nil {
+			return nil, errors.ErrDecoding{Format: "HDR", Reason: err.Error()}
+		}
+
+		if line == "\n" {
+			break
+		}
+
+		if strings.HasPrefix(line, "FORMAT=") && strings.TrimSpace(line) != "FORMAT=32-bit_rle_rgbe" {
+			return nil, errors.ErrDecoding{Format: "HDR", Reason: "unsupported " + strings.TrimSpace(line)}
+		}
+	}
+
+	var width, height int
+	if _, err := fmt.Fscanf(in, "-Y %d +X %d\n", &height, &width); err != nil || width <= 0 || height <= 0 {
+		return nil, errors.ErrDecoding{Format: "HDR", Reason: "unsupported resolution line"}
+	}
+
+	img := domain.NewFloatImage(image.Rect(0, 0, width, height))
+	row := make([]byte, 4*width)
+
+	for y := 0; y < height; y++ {
+		if _, err := io.ReadFull(in, row); err != nil {
+			return nil, errors.ErrDecoding{Format: "HDR", Reason: err.Error()}
+		}
+
+		if width >= 8 && row[0] == 2 && row[1] == 2 && row[2]&0x80 == 0 {
+			return nil, errors.ErrDecoding{Format: "HDR", Reason: "run-length encoded scanlines are not supported"}
+		}
+
+		for x := 0; x < width; x++ {
+			rgb := fromRGBE([4]byte(row[4*x : 4*x+4]))
+			img.SetFloat(x, y, rgb[0], rgb[1], rgb[2])
+		}
+	}
+
+	return img, nil
+}
+
+// toRGBE - общая экспонента по наибольшей компоненте и восьмибитные мантиссы.
+func toRGBE(rgb [3]float32) [4]byte {
+	v := math.Max(float64(rgb[0]), math.Max(float64(rgb[1]), float64(rgb[2])))
+	if v < 1e-32 {
+		return [4]byte{}
+	}
+
+	mantissa, exponent := math.Frexp(v)
+	scale := mantissa * 256 / v
+
+	return [4]byte{
+		byte(math.Max(0, float64(rgb[0])*scale)),
+		byte(math.Max(0, float64(rgb[1])*scale)),
+		byte(math.Max(0, float64(rgb[2])*scale)),
+		byte(exponent + 128),
+	}
+}
+
+// fromRGBE - обратное преобразование, мантисса берется по середине интервала квантования.
+func fromRGBE(rgbe [4]byte) [3]float32 {
+	if rgbe[3] == 0 {
+		return [3]float32{}
+	}
+
+	f := math.Ldexp(1, int(rgbe[3])-(128+8))
+
+	return [3]float32{
+		float32((float64(rgbe[0]) + 0.5) * f),
+		float32((float64(rgbe[1]) + 0.5) * f),
+		float32((float64(rgbe[2]) + 0.5) * f),
+	}
+}
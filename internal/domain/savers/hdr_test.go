@@ -0,0 +1,113 @@
+package savers_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/savers"
+)
+
+// hdrSample - изображение 3x2 с нулем, значениями меньше и больше единицы и очень яркой точкой.
+func hdrSample() *domain.FloatImage {
+	img := domain.NewFloatImage(image.Rect(0, 0, 3, 2))
+	img.SetFloat(1, 0, 0.25, 0.5, 0.75)
+	img.SetFloat(2, 0, 1.5, 0.01, 3)
+	img.SetFloat(0, 1, 1000, 250, 0.125)
+	img.SetFloat(2, 1, 1e-3, 2e-3, 4e-3)
+
+	return img
+}
+
+func TestPfm_RoundTripIsExact(t *testing.T) {
+	src := hdrSample()
+
+	var buf bytes.Buffer
+	if err := savers.EncodePfm(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := savers.DecodePfm(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Bounds() != src.Bounds() {
+		t.Fatalf("decoded bounds %v, want %v", decoded.Bounds(), src.Bounds())
+	}
+
+	for i, v := range src.Pix {
+		if decoded.Pix[i] != v {
+			t.Errorf("component %d: decoded %g, want %g", i, decoded.Pix[i], v)
+		}
+	}
+}
+
+func TestHdr_RoundTripKeepsRelativePrecision(t *testing.T) {
+	src := hdrSample()
+
+	var buf bytes.Buffer
+	if err := savers.EncodeHdr(&buf, src, savers.Metadata{"Seed": "42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "\nSeed=42\n") {
+		t.Error("metadata is missing from the header")
+	}
+
+	decoded, err := savers.DecodeHdr(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := src.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b := src.FloatAt(x, y)
+			dr, dg, db := decoded.FloatAt(x, y)
+
+			// Общая экспонента дает 8 бит мантиссы относительно наибольшей компоненты пикселя.
+			tolerance := math.Max(float64(r), math.Max(float64(g), float64(b))) / 128
+			for _, pair := range [][2]float32{{r, dr}, {g, dg}, {b, db}} {
+				if math.Abs(float64(pair[0]-pair[1])) > tolerance {
+					t.Errorf("pixel (%d, %d): decoded %v, want %v", x, y, [3]float32{dr, dg, db}, [3]float32{r, g, b})
+				}
+			}
+		}
+	}
+}
+
+func TestPfm_NormalisesEightBitImages(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, G: 51, A: 255})
+
+	var buf bytes.Buffer
+	if err := savers.EncodePfm(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := savers.DecodePfm(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r, g, b := decoded.FloatAt(0, 0); r != 1 || math.Abs(float64(g)-0.2) > 1e-6 || b != 0 {
+		t.Errorf("decoded (%g, %g, %g), want (1, 0.2, 0)", r, g, b)
+	}
+}
+
+func TestDecoders_RejectOtherFormats(t *testing.T) {
+	if _, err := savers.DecodePfm(strings.NewReader("Pf\n1 1\n-1.0\n\x00\x00\x00\x00")); !errors.As(err, &domainErrors.ErrDecoding{}) {
+		t.Errorf("greyscale PFM: got %v, want ErrDecoding", err)
+	}
+
+	if _, err := savers.DecodeHdr(strings.NewReader("\x89PNG\r\n")); !errors.As(err, &domainErrors.ErrDecoding{}) {
+		t.Errorf("PNG as HDR: got %v, want ErrDecoding", err)
+	}
+}
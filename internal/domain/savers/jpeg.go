@@ -1,6 +1,7 @@
 package savers
 
 import (
+	"bytes"
 	"image"
 	"image/jpeg"
 	"os"
@@ -9,16 +10,22 @@ import (
 type JpegSaver struct {
 }
 
-// Save - позволяет сохранить изображение в формате jpg.
-func (j *JpegSaver) Save(img image.Image) error {
-	file, err := os.Create("FractalFlame.jpg")
+// Save - позволяет сохранить изображение в формате jpg в файл name.jpg, метаданные записываются в комментарий.
+func (j *JpegSaver) Save(img image.Image, name string, meta Metadata) error {
+	var buf bytes.Buffer
+
+	options := &jpeg.Options{Quality: 100}
+	if err := jpeg.Encode(&buf, img, options); err != nil {
+		return err
+	}
+
+	file, err := os.Create(name + ".jpg")
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	options := &jpeg.Options{Quality: 100}
-	if err := jpeg.Encode(file, img, options); err != nil {
+	if _, err := file.Write(withJpegComment(buf.Bytes(), meta)); err != nil {
 		return err
 	}
 
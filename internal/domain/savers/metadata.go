@@ -0,0 +1,94 @@
+package savers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+// Metadata - текстовые метаданные изображения: параметры рендера, камера и т.п.
+type Metadata map[string]string
+
+// pngHeaderLength - длина сигнатуры PNG вместе с чанком IHDR, после которого вставляются текстовые чанки.
+const pngHeaderLength = 8 + 4 + 4 + 13 + 4
+
+// jpegCommentLimit - максимальная длина комментария в одном сегменте COM.
+const jpegCommentLimit = 0xFFFF - 2
+
+// keys - возвращает ключи в отсортированном порядке, чтобы файл не зависел от порядка обхода map.
+func (m Metadata) keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// withPngText - вставляет метаданные в закодированный PNG в виде чанков tEXt сразу после IHDR.
+func withPngText(encoded []byte, meta Metadata) []byte {
+	if len(meta) == 0 || len(encoded) < pngHeaderLength {
+		return encoded
+	}
+
+	var out bytes.Buffer
+
+	out.Write(encoded[:pngHeaderLength])
+
+	for _, key := range meta.keys() {
+		data := append(append([]byte(key), 0), meta[key]...)
+		_ = writePngChunk(&out, "tEXt", data)
+	}
+
+	out.Write(encoded[pngHeaderLength:])
+
+	return out.Bytes()
+}
+
+func writePngChunk(out io.Writer, chunkType string, data []byte) error {
+	chunk := make([]byte, 0, len(data)+12)
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	_, err := out.Write(chunk)
+
+	return err
+}
+
+// withJpegComment - вставляет метаданные в закодированный JPEG в виде сегмента COM сразу после маркера SOI.
+func withJpegComment(encoded []byte, meta Metadata) []byte {
+	if len(meta) == 0 || len(encoded) < 2 {
+		return encoded
+	}
+
+	var comment bytes.Buffer
+
+	for _, key := range meta.keys() {
+		comment.WriteString(key + ": " + meta[key] + "\n")
+	}
+
+	text := comment.Bytes()
+	if len(text) > jpegCommentLimit {
+		text = text[:jpegCommentLimit]
+	}
+
+	var out bytes.Buffer
+
+	out.Write(encoded[:2])
+	out.Write([]byte{0xFF, 0xFE})
+
+	var length [2]byte
+
+	binary.BigEndian.PutUint16(length[:], uint16(len(text)+2))
+	out.Write(length[:])
+	out.Write(text)
+	out.Write(encoded[2:])
+
+	return out.Bytes()
+}
@@ -0,0 +1,98 @@
+package savers_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"FractalFlame/internal/domain/savers"
+)
+
+var sampleMetadata = savers.Metadata{"Seed": "42", "Camera": "center (0.1, -0.2), scale 150", "Software": "FractalFlame"}
+
+// pngText - текстовые чанки PNG, контрольные суммы всех чанков проверяются.
+func pngText(t *testing.T, data []byte) savers.Metadata {
+	t.Helper()
+
+	text := savers.Metadata{}
+
+	for pos := 8; pos < len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunk := data[pos+4 : pos+8+length]
+
+		if crc := binary.BigEndian.Uint32(data[pos+8+length:]); crc != crc32.ChecksumIEEE(chunk) {
+			t.Fatalf("chunk %q has a wrong CRC", chunk[:4])
+		}
+
+		if string(chunk[:4]) == "tEXt" {
+			key, value, _ := bytes.Cut(chunk[4:], []byte{0})
+			text[string(key)] = string(value)
+		}
+
+		pos += 12 + length
+	}
+
+	return text
+}
+
+func TestPngSaver_MetadataRoundTrip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "flame")
+
+	if err := (&savers.PngSaver{}).Save(streamSample(12, 8), name, sampleMetadata); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(name + ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pngText(t, data); !reflect.DeepEqual(got, sampleMetadata) {
+		t.Errorf("text chunks %v, want %v", got, sampleMetadata)
+	}
+
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("PNG with text chunks does not decode: %v", err)
+	}
+}
+
+func TestJpegSaver_MetadataRoundTrip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "flame")
+
+	if err := (&savers.JpegSaver{}).Save(image.NewNRGBA(image.Rect(0, 0, 12, 8)), name, sampleMetadata); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(name + ".jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF, 0xFE}) {
+		t.Fatalf("comment segment does not follow SOI: % x", data[:4])
+	}
+
+	length := int(binary.BigEndian.Uint16(data[4:]))
+	comment := savers.Metadata{}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(data[6:4+length]), "\n"), "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		comment[key] = value
+	}
+
+	if !reflect.DeepEqual(comment, sampleMetadata) {
+		t.Errorf("comment %v, want %v", comment, sampleMetadata)
+	}
+
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("JPEG with a comment does not decode: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package savers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/errors"
+)
+
+// PfmSaver - сохраняет линейные данные изображения в Portable Float Map: три float32 на пиксель, строки
+// снизу вверх, порядок байтов little-endian (отрицательный масштаб в заголовке).
+type PfmSaver struct{}
+
+// Save - сохраняет изображение в файл name.pfm. В формате нет места для метаданных, поэтому они не пишутся.
+func (p *PfmSaver) Save(img image.Image, name string, _ Metadata) error {
+	file, err := os.Create(name + ".pfm")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return EncodePfm(file, img)
+}
+
+// EncodePfm - записывает изображение в формате PFM. Компоненты FloatImage пишутся без изменений, обычное
+// изображение переводится в [0; 1].
+func EncodePfm(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	out := bufio.NewWriter(w)
+
+	if _, err := fmt.Fprintf(out, "PF\n%d %d\n-1.0\n", bounds.Dx(), bounds.Dy()); err != nil {
+		return err
+	}
+
+	row := make([]byte, 12*bounds.Dx())
+
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			rgb := linearAt(img, x, y)
+
+			for c, v := range rgb {
+				binary.LittleEndian.PutUint32(row[12*(x-bounds.Min.X)+4*c:], math.Float32bits(v))
+			}
+		}
+
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// DecodePfm - читает цветной Portable Float Map с любым порядком байтов.
+func DecodePfm(r io.Reader) (*domain.FloatImage, error) {
+	in := bufio.NewReader(r)
+
+	var (
+		magic         string
+		width, height int
+		scale         float64
+	)
+
+	if _, err := fmt.Fscanf(in, "%s\n%d %d\n%g", &magic, &width, &height, &scale); err != nil {
+		return nil, errors.ErrDecoding{Format: "PFM", Reason: err.Error()}
+	}
+
+	if magic != "PF" {
+		return nil, errors.ErrDecoding{Format: "PFM", Reason: "only colour \"PF\" files are supported, got " + magic}
+	}
+
+	if width <= 0 || height <= 0 {
+		return nil, errors.ErrDecoding{Format: "PFM", Reason: "invalid image size"}
+	}
+
+	if _, err := in.ReadByte(); err != nil {
+		return nil, errors.ErrDecoding{Format: "PFM", Reason: err.Error()}
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	img := domain.NewFloatImage(image.Rect(0, 0, width, height))
+	row := make([]byte, 12*width)
+
+	for y := height - 1; y >= 0; y-- {
+		if _, err := io.ReadFull(in, row); err != nil {
+			return nil, errors.ErrDecoding{Format: "PFM", Reason: err.Error()}
+		}
+
+		for x := 0; x < width; x++ {
+			img.SetFloat(x, y,
+				math.Float32frombits(order.Uint32(row[12*x:])),
+				math.Float32frombits(order.Uint32(row[12*x+4:])),
+				math.Float32frombits(order.Uint32(row[12*x+8:])))
+		}
+	}
+
+	return img, nil
+}
+
+// linearAt - линейные компоненты пикселя: из FloatImage они берутся как есть, цвет обычного изображения
+// переводится в [0; 1].
+func linearAt(img image.Image, x, y int) [3]float32 {
+	if f, ok := img.(*domain.FloatImage); ok {
+		r, g, b := f.FloatAt(x, y)
+
+		return [3]float32{r, g, b}
+	}
+
+	r, g, b, _ := img.At(x, y).RGBA()
+
+	return [3]float32{float32(r) / math.MaxUint16, float32(g) / math.MaxUint16, float32(b) / math.MaxUint16}
+}
@@ -1,25 +1,60 @@
 package savers
 
 import (
+	"bytes"
 	"image"
+	"image/draw"
 	"image/png"
 	"os"
 )
 
-type PngSaver struct{}
+// PngSaver - сохраняет изображение в PNG с BitDepth бит на канал: 16 или 8 (по умолчанию).
+type PngSaver struct {
+	BitDepth int
+}
+
+// Save позволяет сохранить изображение в формате PNG в файл name.png, метаданные записываются в текстовые чанки.
+func (p *PngSaver) Save(img image.Image, name string, meta Metadata) error {
+	var buf bytes.Buffer
 
-// Save позволяет сохранить изображение в формате PNG.
-func (p *PngSaver) Save(img image.Image) error {
-	file, err := os.Create("FractalFlame.png")
+	if err := png.Encode(&buf, p.withBitDepth(img)); err != nil {
+		return err
+	}
+
+	file, err := os.Create(name + ".png")
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	err = png.Encode(file, img)
-	if err != nil {
+	if _, err := file.Write(withPngText(buf.Bytes(), meta)); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// withBitDepth - приводит изображение к глубине BitDepth: кодировщик PNG выбирает глубину по типу изображения,
+// 16 бит на канал он записывает только для 16-битных типов.
+func (p *PngSaver) withBitDepth(img image.Image) image.Image {
+	var converted draw.Image
+
+	switch img.(type) {
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16:
+		if p.BitDepth == 16 {
+			return img
+		}
+
+		converted = image.NewNRGBA(img.Bounds())
+	default:
+		if p.BitDepth != 16 {
+			return img
+		}
+
+		converted = image.NewNRGBA64(img.Bounds())
+	}
+
+	draw.Draw(converted, converted.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	return converted
+}
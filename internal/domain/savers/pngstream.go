@@ -0,0 +1,233 @@
+package savers
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// pngSignature - сигнатура в начале каждого PNG-файла.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// idatChunkSize - размер чанков IDAT, на которые режутся сжатые данные.
+const idatChunkSize = 1 << 16
+
+// PngStream - кодировщик PNG, который принимает изображение полосами строк и сразу сжимает их в поток, не
+// храня изображение целиком. Пишет RGBA с BitDepth 8 или 16 бит на канал, метаданные - в чанки tEXt.
+type PngStream struct {
+	width    int
+	height   int
+	rows     int
+	bitDepth int
+	idat     *bufio.Writer
+	zlib     *zlib.Writer
+	chunks   *idatWriter
+	previous []byte
+	current  []byte
+	filtered [5][]byte
+}
+
+// idatWriter - пишет каждый полученный кусок сжатых данных отдельным чанком IDAT.
+type idatWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (c *idatWriter) Write(data []byte) (int, error) {
+	if c.err == nil {
+		c.err = writePngChunk(c.w, "IDAT", data)
+	}
+
+	return len(data), c.err
+}
+
+// NewPngStream - начинает PNG размером width x height с глубиной bitDepth (16 или 8) и метаданными meta:
+// записывает сигнатуру, заголовок и текстовые чанки. Строки передаются методом WriteRows, файл завершает Close.
+func NewPngStream(w io.Writer, width, height, bitDepth int, meta Metadata) (*PngStream, error) {
+	if bitDepth != 16 {
+		bitDepth = 8
+	}
+
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:], uint32(width))
+	binary.BigEndian.PutUint32(header[4:], uint32(height))
+	header[8] = byte(bitDepth)
+	header[9] = 6 // RGBA
+
+	if _, err := io.WriteString(w, pngSignature); err != nil {
+		return nil, err
+	}
+
+	if err := writePngChunk(w, "IHDR", header); err != nil {
+		return nil, err
+	}
+
+	for _, key := range meta.keys() {
+		if err := writePngChunk(w, "tEXt", append(append([]byte(key), 0), meta[key]...)); err != nil {
+			return nil, err
+		}
+	}
+
+	stride := width * 4 * bitDepth / 8
+	chunks := &idatWriter{w: w}
+	idat := bufio.NewWriterSize(chunks, idatChunkSize)
+	p := &PngStream{
+		width:    width,
+		height:   height,
+		bitDepth: bitDepth,
+		idat:     idat,
+		zlib:     zlib.NewWriter(idat),
+		chunks:   chunks,
+		previous: make([]byte, stride),
+		current:  make([]byte, stride),
+	}
+
+	for i := range p.filtered {
+		p.filtered[i] = make([]byte, stride+1)
+	}
+
+	return p, nil
+}
+
+// WriteRows - дописывает строки изображения img той же ширины. Строки *image.NRGBA и *image.NRGBA64 подходящей
+// глубины берутся как есть, остальные изображения сначала переводятся в нужный тип.
+func (p *PngStream) WriteRows(img image.Image) error {
+	bounds := img.Bounds()
+
+	switch {
+	case bounds.Dx() != p.width:
+		return errors.ErrEncoding{Format: "PNG", Reason: "row width differs from the image"}
+	case p.rows+bounds.Dy() > p.height:
+		return errors.ErrEncoding{Format: "PNG", Reason: "more rows than the image height"}
+	}
+
+	pix, stride := p.pixels(img)
+
+	for y := 0; y < bounds.Dy(); y++ {
+		copy(p.current, pix[y*stride:])
+
+		if _, err := p.zlib.Write(p.filterRow()); err != nil {
+			return err
+		}
+
+		p.previous, p.current = p.current, p.previous
+		p.rows++
+	}
+
+	return p.chunks.err
+}
+
+// pixels - байты строк изображения в раскладке RGBA нужной глубины и шаг между строками.
+func (p *PngStream) pixels(img image.Image) (pix []byte, stride int) {
+	bounds := img.Bounds()
+
+	switch src := img.(type) {
+	case *image.NRGBA:
+		if p.bitDepth == 8 {
+			return src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride
+		}
+	case *image.NRGBA64:
+		if p.bitDepth == 16 {
+			return src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y):], src.Stride
+		}
+	}
+
+	rect := image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+
+	if p.bitDepth == 16 {
+		converted := image.NewNRGBA64(rect)
+		draw.Draw(converted, rect, img, bounds.Min, draw.Src)
+
+		return converted.Pix, converted.Stride
+	}
+
+	converted := image.NewNRGBA(rect)
+	draw.Draw(converted, rect, img, bounds.Min, draw.Src)
+
+	return converted.Pix, converted.Stride
+}
+
+// filterRow - выбирает для строки фильтр PNG с наименьшей суммой модулей отфильтрованных байтов, как это
+// делает image/png, и возвращает строку с байтом типа фильтра.
+func (p *PngStream) filterRow() []byte {
+	bpp := 4 * p.bitDepth / 8
+	cur, prev := p.current, p.previous
+
+	for i := range p.filtered {
+		p.filtered[i][0] = byte(i)
+	}
+
+	for i := range cur {
+		var left, upLeft byte
+		if i >= bpp {
+			left, upLeft = cur[i-bpp], prev[i-bpp]
+		}
+
+		up := prev[i]
+		p.filtered[0][i+1] = cur[i]
+		p.filtered[1][i+1] = cur[i] - left
+		p.filtered[2][i+1] = cur[i] - up
+		p.filtered[3][i+1] = cur[i] - byte((int(left)+int(up))/2)
+		p.filtered[4][i+1] = cur[i] - paeth(left, up, upLeft)
+	}
+
+	best, bestSum := 0, -1
+
+	for i, row := range p.filtered {
+		sum := 0
+		for _, b := range row[1:] {
+			sum += abs(int(int8(b)))
+		}
+
+		if bestSum < 0 || sum < bestSum {
+			best, bestSum = i, sum
+		}
+	}
+
+	return p.filtered[best]
+}
+
+// Close - завершает сжатые данные и файл. Если записаны не все строки, возвращается ошибка.
+func (p *PngStream) Close() error {
+	if err := p.zlib.Close(); err != nil {
+		return err
+	}
+
+	if err := p.idat.Flush(); err != nil {
+		return err
+	}
+
+	if p.rows != p.height {
+		return errors.ErrEncoding{Format: "PNG", Reason: "image is missing rows"}
+	}
+
+	return writePngChunk(p.chunks.w, "IEND", nil)
+}
+
+// paeth - предсказатель фильтра Paeth из спецификации PNG.
+func paeth(left, up, upLeft byte) byte {
+	estimate := int(left) + int(up) - int(upLeft)
+	dLeft, dUp, dUpLeft := abs(estimate-int(left)), abs(estimate-int(up)), abs(estimate-int(upLeft))
+
+	switch {
+	case dLeft <= dUp && dLeft <= dUpLeft:
+		return left
+	case dUp <= dUpLeft:
+		return up
+	default:
+		return upLeft
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
@@ -0,0 +1,112 @@
+package savers_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/savers"
+)
+
+// streamSample - изображение с градиентом, полупрозрачными пикселями и резкими переходами, чтобы в сжатии
+// участвовали разные фильтры строк.
+func streamSample(width, height int) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(x * 65535 / width),
+				G: uint16((x*y*977 + 13) % 65536),
+				B: uint16(y * 65535 / height),
+				A: uint16(65535 - (x+y)%3*20000),
+			})
+		}
+	}
+
+	return img
+}
+
+// encodeInBands - кодирует изображение потоком полосами по rows строк.
+func encodeInBands(t *testing.T, img *image.NRGBA64, bitDepth, rows int) *bytes.Buffer {
+	t.Helper()
+
+	bounds := img.Bounds()
+
+	var buf bytes.Buffer
+
+	stream, err := savers.NewPngStream(&buf, bounds.Dx(), bounds.Dy(), bitDepth, savers.Metadata{"Seed": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for top := 0; top < bounds.Dy(); top += rows {
+		band := image.Rect(0, top, bounds.Dx(), min(top+rows, bounds.Dy()))
+		if err := stream.WriteRows(img.SubImage(band)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &buf
+}
+
+func TestPngStream_DecodesToTheSameImage(t *testing.T) {
+	src := streamSample(37, 23)
+
+	for _, bitDepth := range []int{8, 16} {
+		buf := encodeInBands(t, src, bitDepth, 5)
+
+		if !bytes.Contains(buf.Bytes(), []byte("tEXtSeed\x0042")) {
+			t.Errorf("%d-bit stream: metadata chunk is missing", bitDepth)
+		}
+
+		decoded, err := png.Decode(buf)
+		if err != nil {
+			t.Fatalf("%d-bit stream: %v", bitDepth, err)
+		}
+
+		var want draw.Image = src
+		if bitDepth == 8 {
+			want = image.NewNRGBA(src.Bounds())
+			draw.Draw(want, src.Bounds(), src, image.Point{}, draw.Src)
+		}
+
+		for y := 0; y < src.Bounds().Dy(); y++ {
+			for x := 0; x < src.Bounds().Dx(); x++ {
+				if got, exp := color.NRGBA64Model.Convert(decoded.At(x, y)), color.NRGBA64Model.Convert(want.At(x, y)); got != exp {
+					t.Fatalf("%d-bit stream: pixel (%d, %d) is %v, want %v", bitDepth, x, y, got, exp)
+				}
+			}
+		}
+	}
+}
+
+func TestPngStream_RejectsMissingRows(t *testing.T) {
+	var buf bytes.Buffer
+
+	stream, err := savers.NewPngStream(&buf, 4, 4, 8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.WriteRows(image.NewNRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.Close(); !errors.As(err, &domainErrors.ErrEncoding{}) {
+		t.Errorf("closing an incomplete image: got %v, want ErrEncoding", err)
+	}
+
+	if err := stream.WriteRows(image.NewNRGBA(image.Rect(0, 0, 5, 1))); !errors.As(err, &domainErrors.ErrEncoding{}) {
+		t.Errorf("row of another width: got %v, want ErrEncoding", err)
+	}
+}
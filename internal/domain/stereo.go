@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"image"
+	"math/rand/v2"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+// StereoLayout - способ расположения кадров левого и правого глаза в изображении.
+type StereoLayout int
+
+const (
+	// StereoSideBySide - левый кадр слева, правый справа.
+	StereoSideBySide StereoLayout = iota + 1
+	// StereoOverUnder - левый кадр сверху, правый снизу.
+	StereoOverUnder
+	// StereoAnaglyph - красно-голубой анаглиф: красный канал из левого кадра, зеленый и синий из правого.
+	StereoAnaglyph
+)
+
+// Stereo - параметры стереопары: расстояние между глазами в единицах пламени и расположение кадров.
+type Stereo struct {
+	Separation float64
+	Layout     StereoLayout
+}
+
+// ParseStereoLayout - разбирает расположение стереопары из конфигурации.
+func ParseStereoLayout(name string) (StereoLayout, error) {
+	switch name {
+	case "side-by-side":
+		return StereoSideBySide, nil
+	case "over-under":
+		return StereoOverUnder, nil
+	case "anaglyph":
+		return StereoAnaglyph, nil
+	}
+
+	return 0, errors.ErrUnknownStereoLayout{Name: name}
+}
+
+func (l StereoLayout) String() string {
+	switch l {
+	case StereoSideBySide:
+		return "side-by-side"
+	case StereoOverUnder:
+		return "over-under"
+	case StereoAnaglyph:
+		return "anaglyph"
+	default:
+		return "none"
+	}
+}
+
+// StereoResolution - размер буфера для стереопары с кадрами width x height: кадры хранятся рядом, а при
+// расположении один над другим - друг под другом. Анаглиф собирается из кадров, лежащих рядом.
+func StereoResolution(layout StereoLayout, width, height int) (bufferWidth, bufferHeight int) {
+	if layout == StereoOverUnder {
+		return width, 2 * height
+	}
+
+	return 2 * width, height
+}
+
+// SetStereo - включает рендер стереопары для трехмерного пламени: за один проход игры хаоса каждая точка
+// проецируется для левого и правого глаза, смещенных на ±separation/2, и попадает в оба кадра. Нулевой
+// параллакс приходится на плоскость z = 0 камеры, поэтому для объема нужна ненулевая перспектива. Размер
+// изображения должен быть получен через StereoResolution, камера плоскости сбрасывается и задается после.
+func (im *ImageMatrix) SetStereo(separation float64, layout StereoLayout) error {
+	if im.Camera3D == nil {
+		return errors.ErrStereoUnsupported{Reason: "stereo requires a 3D camera"}
+	}
+
+	if im.projection != ProjectionPlane {
+		return errors.ErrStereoUnsupported{Reason: "stereo is not available for " + im.projection.String() + " projection"}
+	}
+
+	im.stereo = &Stereo{Separation: separation, Layout: layout}
+	im.SetCamera(Camera{})
+
+	return nil
+}
+
+// Stereo - возвращает параметры стереопары или nil, если она выключена.
+func (im *ImageMatrix) Stereo() *Stereo {
+	return im.stereo
+}
+
+// frame - размер одного кадра: совпадает с размером изображения, а для стереопары - это кадр одного глаза.
+func (im *ImageMatrix) frame() (width, height int) {
+	switch {
+	case im.stereo == nil:
+		return im.Resolution.Width, im.Resolution.Height
+	case im.stereo.Layout == StereoOverUnder:
+		return im.Resolution.Width, im.Resolution.Height / 2
+	default:
+		return im.Resolution.Width / 2, im.Resolution.Height
+	}
+}
+
+// frameOffsets - левые верхние углы кадров в буфере изображения.
+func (im *ImageMatrix) frameOffsets() [][2]int {
+	width, height := im.frame()
+
+	switch {
+	case im.stereo == nil:
+		return [][2]int{{0, 0}}
+	case im.stereo.Layout == StereoOverUnder:
+		return [][2]int{{0, 0}, {0, height}}
+	default:
+		return [][2]int{{0, 0}, {width, 0}}
+	}
+}
+
+// plotStereo - проецирует точку для обоих глаз и обновляет пиксели в кадрах левого и правого глаза.
+func (im *ImageMatrix) plotStereo(rng *rand.Rand, x, y, z float64, linearCoeffs *AffineTransformation) {
+	leftX, rightX, projY, ok := im.Camera3D.ProjectStereo(rng, x, y, z, im.stereo.Separation)
+	if !ok {
+		return
+	}
+
+	offsets := im.frameOffsets()
+
+	for eye, eyeX := range [2]float64{leftX, rightX} {
+		fx, fy := im.view.toPixel(eyeX, projY)
+		im.plotPixel(fx, fy, offsets[eye], linearCoeffs)
+	}
+}
+
+// convertAnaglyph - собирает красно-голубой анаглиф из кадров левого и правого глаза.
+func (im *ImageMatrix) convertAnaglyph() image.Image {
+	width, height := im.frame()
+	img, set := im.outputImage(nil, width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			left, right := im.outputColour(im.Pixels[y][x].Colour, y), im.outputColour(im.Pixels[y][width+x].Colour, y)
+
+			set(x, y, FloatColour{left[0], right[1], right[2], max(left[3], right[3])})
+		}
+	}
+
+	return img
+}
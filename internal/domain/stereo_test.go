@@ -0,0 +1,90 @@
+package domain_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+func TestProjectStereo_ParallaxDependsOnDepth(t *testing.T) {
+	camera := domain.NewCamera3D(0, 0, 0.5, 0)
+	rng := random.New(1, 0)
+
+	cases := []struct {
+		name  string
+		z     float64
+		check func(left, right float64) bool
+	}{
+		{"zero parallax plane", 0, func(left, right float64) bool { return math.Abs(left-right) < 1e-12 }},
+		{"behind the screen", -2, func(left, right float64) bool { return left < right }},
+		{"in front of the screen", 1, func(left, right float64) bool { return left > right }},
+	}
+
+	for _, c := range cases {
+		left, right, _, ok := camera.ProjectStereo(rng, 0.3, 0.1, c.z, 0.2)
+		if !ok || !c.check(left, right) {
+			t.Errorf("%s: left eye %v, right eye %v, ok %v", c.name, left, right, ok)
+		}
+	}
+}
+
+func TestSetStereo_RendersBothEyesInOnePass(t *testing.T) {
+	width, height := domain.StereoResolution(domain.StereoAnaglyph, 64, 48)
+
+	im := domain.NewImageMatrix(width, height, 8, 20000)
+	im.Seed = 7
+	im.Camera3D = domain.NewCamera3D(30, 20, 0.4, 0)
+
+	if err := im.SetStereo(0.3, domain.StereoAnaglyph); err != nil {
+		t.Fatal(err)
+	}
+
+	im.GenerateAffineTransformations()
+
+	for i := range im.LinearTransformations {
+		im.LinearTransformations[i].Z = &domain.ZAffine{A: 0.3, B: -0.2, C: 0.5, D: 0.1}
+	}
+
+	im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Linear, transformations.Spherical)
+
+	rng := random.NewWorkerRand()
+	for i := 0; i < im.StartingPoints; i++ {
+		im.ProcessStartingPoint(i, rng)
+	}
+
+	var left, right, different int
+
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			l, r := im.Pixels[y][x].HitRate, im.Pixels[y][64+x].HitRate
+			left += l
+			right += r
+
+			if l != r {
+				different++
+			}
+		}
+	}
+
+	if left == 0 || right == 0 || different == 0 {
+		t.Errorf("left eye %d hits, right eye %d hits, %d pixels differ", left, right, different)
+	}
+
+	if b := im.ConvertToImage().Bounds(); b.Dx() != 64 || b.Dy() != 48 {
+		t.Errorf("anaglyph is %dx%d, want 64x48", b.Dx(), b.Dy())
+	}
+}
+
+func TestSetStereo_RequiresCamera3D(t *testing.T) {
+	im := domain.NewImageMatrix(64, 32, 1, 1)
+
+	err := im.SetStereo(0.1, domain.StereoSideBySide)
+	if !errors.As(err, &domainErrors.ErrStereoUnsupported{}) {
+		t.Errorf("got %v, want ErrStereoUnsupported", err)
+	}
+}
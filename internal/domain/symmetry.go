@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"math"
+
+	"FractalFlame/internal/domain/transformations"
+)
+
+// AddSymmetry - добавляет в пламя преобразования симметрии, которые участвуют в игре хаоса наравне
+// с остальными: при order = N > 1 это повороты на 2πk/N (вращательная симметрия), при order = -N
+// дополнительно отражение x -> -x (диэдральная симметрия). Преобразования симметрии не меняют цвет точки.
+func (im *ImageMatrix) AddSymmetry(order int) {
+	if order == 0 || order == 1 {
+		return
+	}
+
+	im.Symmetry = order
+
+	if order < 0 {
+		im.LinearTransformations = append(im.LinearTransformations, symmetryTransformation(-1, 0, 0, 1))
+		order = -order
+	}
+
+	for k := 1; k < order; k++ {
+		angle := 2 * math.Pi * float64(k) / float64(order)
+		cos, sin := math.Cos(angle), math.Sin(angle)
+
+		im.LinearTransformations = append(im.LinearTransformations, symmetryTransformation(cos, -sin, sin, cos))
+	}
+}
+
+// symmetryTransformation - создает преобразование симметрии с линейной частью x' = a*x + b*y, y' = c*x + d*y.
+func symmetryTransformation(a, b, c, d float64) AffineTransformation {
+	return AffineTransformation{
+		A:          a,
+		B:          b,
+		D:          d,
+		E:          c,
+		Variations: []Variation{{Name: "Linear", Weight: 1, Func: transformations.Linear}},
+		Symmetry:   true,
+	}
+}
@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+)
+
+func TestAddSymmetry_CopiesKeepSourceColour(t *testing.T) {
+	for _, order := range []int{24, -24} {
+		// У одного обычного преобразования из 24 первые шаги точки часто приходятся только на симметрию.
+		im := setUpFlame(domain.NewImageMatrix(48, 32, 64, 500), 3)
+		im.LinearTransformations = im.LinearTransformations[:1]
+		im.LinearTransformations[0].TransformationColour = color.RGBA{R: 200, G: 40, A: 255}
+		im.AddSymmetry(order)
+
+		renderPoints(im, 0, im.StartingPoints)
+		im.ToneMap(0)
+
+		want := domain.FloatColour{200.0 / 255, 40.0 / 255, 0, 1}
+		hits := 0
+
+		for y := range im.Pixels {
+			for x := range im.Pixels[y] {
+				pixel := &im.Pixels[y][x]
+				if pixel.HitRate == 0 {
+					continue
+				}
+
+				hits++
+
+				for c := range want {
+					if math.Abs(pixel.Colour[c]-want[c]) > 1e-9 {
+						t.Fatalf("symmetry %d: pixel (%d, %d) has colour %v, want the source colour %v", order, x, y, pixel.Colour, want)
+					}
+				}
+			}
+		}
+
+		if hits == 0 {
+			t.Fatalf("symmetry %d: nothing was plotted", order)
+		}
+	}
+}
+
+func TestAddSymmetry_RotatesHistogram(t *testing.T) {
+	im := domain.NewImageMatrix(64, 64, 16, 5000)
+	setUpFlame(im, 9)
+	im.SetCamera(domain.Camera{Zoom: -0.5})
+	im.AddSymmetry(4)
+
+	renderPoints(im, 0, im.StartingPoints)
+
+	// Поворот на 90° вокруг центра переводит пиксель (x, y) в (63 - y, x).
+	var total, matched int
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			if im.Pixels[y][x].HitRate == 0 {
+				continue
+			}
+
+			total++
+
+			if im.Pixels[x][63-y].HitRate > 0 {
+				matched++
+			}
+		}
+	}
+
+	if total == 0 || float64(matched) < 0.9*float64(total) {
+		t.Errorf("%d of %d hit pixels have a hit in the rotated position", matched, total)
+	}
+}
@@ -0,0 +1,77 @@
+package domain_test
+
+import (
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+func luminance(c domain.FloatColour) float64 {
+	return (c[0] + c[1] + c[2]) / 3
+}
+
+// columnDifference - средняя разница яркости между двумя столбцами изображения.
+func columnDifference(im *domain.ImageMatrix, left, right int) float64 {
+	var sum float64
+
+	for y := range im.Pixels {
+		sum += math.Abs(luminance(im.Pixels[y][left].Colour) - luminance(im.Pixels[y][right].Colour))
+	}
+
+	return sum / float64(len(im.Pixels))
+}
+
+func renderTileable(tileable bool) *domain.ImageMatrix {
+	im := domain.NewImageMatrix(96, 64, 16, 20000)
+	im.Seed = 42
+	im.Tileable = tileable
+	im.SetCamera(domain.Camera{Zoom: 1.5})
+	im.GenerateAffineTransformations()
+	im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Linear,
+		transformations.Disc, transformations.Sinusoidal)
+
+	rng := random.NewWorkerRand()
+	for i := 0; i < im.StartingPoints; i++ {
+		im.ProcessStartingPoint(i, rng)
+	}
+
+	im.ToneMap(0)
+	im.Filter(2)
+
+	return im
+}
+
+func TestTileable_LeftAndRightEdgesAreContinuous(t *testing.T) {
+	im := renderTileable(true)
+	width := im.Resolution.Width
+
+	var interior float64
+
+	for x := 0; x < width-1; x++ {
+		interior += columnDifference(im, x, x+1)
+	}
+
+	interior /= float64(width - 1)
+
+	seam := columnDifference(im, width-1, 0)
+	if seam > 2*interior {
+		t.Errorf("seam between right and left edges %.2f, mean difference between neighbouring columns %.2f",
+			seam, interior)
+	}
+}
+
+func TestFilter_WrapsAcrossEdgesInTileableMode(t *testing.T) {
+	im := domain.NewImageMatrix(16, 8, 1, 1)
+	im.Tileable = true
+	im.Pixels[4][0].Colour = domain.FloatColour{1, 1, 1, 1}
+
+	im.Filter(2)
+
+	left, right := im.Pixels[4][1].Colour, im.Pixels[4][im.Resolution.Width-1].Colour
+	if left != right || luminance(right) == 0 {
+		t.Errorf("filter is not symmetric across the seam: left neighbour %v, wrapped neighbour %v", left, right)
+	}
+}
@@ -0,0 +1,70 @@
+package domain
+
+import "math"
+
+// TileBytesPerPixel - оценка памяти на пиксель полосы тайлового рендера: сам пиксель, каналы и буфер фильтра
+// и строка результата.
+const TileBytesPerPixel = 160
+
+// band - положение полосы тайлового рендера в изображении: первая строка и высота всего изображения.
+type band struct {
+	top         int
+	imageHeight int
+}
+
+// NewTiledImageMatrix - буфер изображения width x height без пикселей для тайлового рендера: он хранит
+// преобразования, камеру и параметры вывода, а пиксели выделяются по полосам методом Band.
+func NewTiledImageMatrix(width, height, startingPoints, iterations int) *ImageMatrix {
+	return newImageMatrix(width, height, 0, startingPoints, iterations)
+}
+
+// Band - буфер полосы строк [top; top+rows) изображения. У полосы те же преобразования, seed и параметры
+// вывода, а точки проецируются камерой всего изображения, так что рендер всех стартовых точек в полосу дает
+// ровно ее строки полного рендера. Цвет фона берется по строке всего изображения. Рабочие буферы фильтра
+// полоса хранит у себя и переносит вместе с собой через MoveBand. Проекции, стереопары и бесшовный режим
+// полосы не поддерживают.
+func (im *ImageMatrix) Band(top, rows int) *ImageMatrix {
+	b := newImageMatrix(im.Resolution.Width, rows, rows, im.StartingPoints, im.Iterations)
+	b.cords = im.cords
+	b.LinearTransformations = im.LinearTransformations
+	b.NonLinearTransformations = im.NonLinearTransformations
+	b.Camera3D = im.Camera3D
+	b.Seed = im.Seed
+	b.Symmetry = im.Symmetry
+	b.Background = im.Background
+	b.PremultipliedAlpha = im.PremultipliedAlpha
+	b.BitDepth = im.BitDepth
+	b.wallpaper = im.wallpaper
+	b.hyperbolic = im.hyperbolic
+	b.camera = im.camera
+	b.view = im.view
+	b.band = &band{top: top, imageHeight: im.Resolution.Height}
+	b.scratch = &filterScratch{}
+
+	return b
+}
+
+// MoveBand - переносит буфер полосы, созданный методом Band, на строки [top; top+rows) того же изображения
+// без выделения памяти: накопленные данные пикселей сбрасываются. Строк должно быть не больше, чем при создании
+// полосы.
+func (im *ImageMatrix) MoveBand(top, rows int) {
+	im.Pixels = im.Pixels[:rows]
+	im.Resolution.Height = rows
+	im.band.top = top
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			im.Pixels[y][x] = Pixel{X: x, Y: y}
+		}
+	}
+}
+
+// BandMargin - сколько строк соседних полос нужно фильтру радиуса radius, чтобы края полосы сгладились так
+// же, как в полном изображении.
+func BandMargin(radius float64) int {
+	if radius <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(radius))
+}
@@ -0,0 +1,119 @@
+package domain_test
+
+import (
+	"image"
+	"testing"
+
+	"FractalFlame/internal/domain"
+)
+
+func TestBand_TiledRenderMatchesFullImage(t *testing.T) {
+	const (
+		gamma  = 2.2
+		radius = 1.5
+		rows   = 7
+	)
+
+	background, err := domain.ParseBackground("#102030,#a0b0c0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full := histogramFlame(6)
+	full.Background = background
+	renderPoints(full, 0, full.StartingPoints)
+	full.ToneMap(gamma)
+	full.Filter(radius)
+	want := full.ConvertToImage().(*image.NRGBA)
+
+	tiled := setUpFlame(domain.NewTiledImageMatrix(48, 32, 4, 5000), 6)
+	tiled.Background = background
+
+	if len(tiled.Pixels) != 0 {
+		t.Fatalf("tiled image allocates %d rows of pixels", len(tiled.Pixels))
+	}
+
+	margin := domain.BandMargin(radius)
+	height := tiled.Resolution.Height
+
+	var bands []*domain.ImageMatrix
+
+	maxHits := 0
+
+	for top := 0; top < height; top += rows {
+		from, to := max(top-margin, 0), min(top+rows+margin, height)
+		band := tiled.Band(from, to-from)
+		renderPoints(band, 0, band.StartingPoints)
+
+		bands = append(bands, band)
+		maxHits = max(maxHits, band.MaxHits())
+	}
+
+	for i, band := range bands {
+		top := i * rows
+		skip := top - max(top-margin, 0)
+
+		band.ToneMapMax(gamma, maxHits)
+		band.Filter(radius)
+		got := band.ConvertToImage().(*image.NRGBA)
+
+		for y := top; y < min(top+rows, height); y++ {
+			for x := 0; x < tiled.Resolution.Width; x++ {
+				if got.NRGBAAt(x, y-top+skip) != want.NRGBAAt(x, y) {
+					t.Fatalf("pixel (%d, %d) of band %d is %v, full render has %v", x, y, i, got.NRGBAAt(x, y-top+skip), want.NRGBAAt(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestMoveBand_ReusedBufferMatchesNewBand(t *testing.T) {
+	tiled := setUpFlame(domain.NewTiledImageMatrix(48, 32, 4, 5000), 6)
+	buffer := tiled.Band(0, 12)
+
+	var img image.Image
+
+	for _, band := range [][2]int{{0, 12}, {10, 12}, {26, 6}} {
+		fresh := tiled.Band(band[0], band[1])
+		renderPoints(fresh, 0, fresh.StartingPoints)
+		fresh.ToneMap(2.2)
+		want := fresh.ConvertToImage().(*image.NRGBA)
+
+		buffer.MoveBand(band[0], band[1])
+		renderPoints(buffer, 0, buffer.StartingPoints)
+		buffer.ToneMap(2.2)
+
+		previous := img
+		img = buffer.ConvertToImageInto(img)
+
+		if previous != nil && img != previous {
+			t.Errorf("band at row %d allocated a new image", band[0])
+		}
+
+		got := img.(*image.NRGBA)
+
+		for y := 0; y < band[1]; y++ {
+			for x := 0; x < 48; x++ {
+				if got.NRGBAAt(x, y) != want.NRGBAAt(x, y) {
+					t.Fatalf("pixel (%d, %d) of the band at row %d is %v, a new band has %v",
+						x, y, band[0], got.NRGBAAt(x, y), want.NRGBAAt(x, y))
+				}
+			}
+		}
+	}
+}
+
+func TestBand_FilterReusesScratchBuffers(t *testing.T) {
+	band := setUpFlame(domain.NewTiledImageMatrix(48, 32, 4, 5000), 6).Band(0, 12)
+	band.Filter(1.5)
+
+	// Выделяется только ядро фильтра: буферы каналов и свертки остаются от прошлого вызова.
+	allocs := testing.AllocsPerRun(10, func() {
+		band.MoveBand(10, 12)
+		band.Filter(1.5)
+	})
+
+	if allocs > 1 {
+		t.Errorf("filter of a moved band made %.0f allocations, want only the kernel", allocs)
+	}
+}
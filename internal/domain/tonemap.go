@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FloatColour - цвет пикселя после тональной компрессии: компоненты R, G, B, умноженные на альфа-канал,
+// и сам альфа-канал, все в диапазоне [0; 1].
+type FloatColour [4]float64
+
+// ToneMap - тональная компрессия накопленной гистограммы. Цвет пикселя - среднее цветов попавших в него
+// преобразований, яркость - логарифм плотности попаданий, нормированный на максимум и возведенный в степень
+// 1/gamma, она же задает альфа-канал. При gamma <= 0 коррекция выключена и каждый закрашенный пиксель
+// получает полную яркость. Результат хранится в числах с плавающей точкой и квантуется только при выводе.
+func (im *ImageMatrix) ToneMap(gamma float64) {
+	im.ToneMapMax(gamma, im.MaxHits())
+}
+
+// ToneMapMax - тональная компрессия, в которой яркость нормируется на заданное наибольшее число попаданий
+// maxHits, а не на максимум этого буфера. Так полосы тайлового рендера получают общую нормировку.
+func (im *ImageMatrix) ToneMapMax(gamma float64, maxHits int) {
+	maxDensity := maxLogDensity(maxHits)
+
+	for row := range im.Pixels {
+		for col := range im.Pixels[row] {
+			pixel := &im.Pixels[row][col]
+			pixel.Colour = toneMapPixel(float64(pixel.HitRate), pixel.colourSum, maxDensity, gamma)
+		}
+	}
+}
+
+// ToneMapLinear - линейные данные для HDR-форматов без тональной компрессии: цвет пикселя - сумма цветов
+// попавших в него преобразований, то есть средний цвет, умноженный на число попаданий, деленная
+// на SamplesPerPixel и умноженная на exposure. При exposure = 1 белый пиксель со средней по кадру плотностью
+// получает 1, более плотные - больше 1, и значение не зависит от длительности рендера. Альфа-канал - та же
+// величина для белого цвета, ограниченная 1, так что редкие попадания лишь частично закрывают фон.
+func (im *ImageMatrix) ToneMapLinear(exposure float64) {
+	density := exposure
+
+	if samples := im.SamplesPerPixel(); samples > 0 {
+		density /= samples
+	}
+
+	scale := density / 255
+
+	for row := range im.Pixels {
+		for col := range im.Pixels[row] {
+			pixel := &im.Pixels[row][col]
+			if pixel.HitRate == 0 {
+				pixel.Colour = FloatColour{}
+
+				continue
+			}
+
+			sum := pixel.colourSum
+			alpha := math.Min(1, float64(pixel.HitRate)*density)
+			pixel.Colour = FloatColour{sum[0] * scale, sum[1] * scale, sum[2] * scale, alpha}
+		}
+	}
+}
+
+// MaxHits - наибольшее число попаданий в пиксель буфера.
+func (im *ImageMatrix) MaxHits() int {
+	var maxHits int
+
+	for row := range im.Pixels {
+		for col := range im.Pixels[row] {
+			maxHits = max(maxHits, im.Pixels[row][col].HitRate)
+		}
+	}
+
+	return maxHits
+}
+
+// maxLogDensity - логарифм наибольшего числа попаданий, на который нормируется яркость. Если логарифм
+// нулевой, возвращается 1, чтобы не делить на ноль.
+func maxLogDensity(maxHits int) float64 {
+	if maxHits <= 1 {
+		return 1
+	}
+
+	return math.Log10(float64(maxHits))
+}
+
+// toneMapPixel - цвет пикселя с hits попаданиями и суммой цветов sum после тональной компрессии.
+func toneMapPixel(hits float64, sum [3]float64, maxDensity, gamma float64) FloatColour {
+	if hits == 0 {
+		return FloatColour{}
+	}
+
+	density := 1.0
+	if gamma > 0 {
+		density = math.Pow(math.Log10(hits)/maxDensity, 1/gamma)
+	}
+
+	scale := density / hits / 255
+
+	return FloatColour{sum[0] * scale, sum[1] * scale, sum[2] * scale, density}
+}
+
+// ConvertToImage - преобразовывает структуру ImageMatrix в картинку с учетом фона, стереопара в режиме
+// анаглифа собирается в один кадр. При BitDepth = 16 получается image.NRGBA64, при FloatBitDepth - FloatImage
+// с линейными компонентами без квантования, иначе image.NRGBA.
+func (im *ImageMatrix) ConvertToImage() image.Image {
+	if im.stereo != nil && im.stereo.Layout == StereoAnaglyph {
+		return im.convertAnaglyph()
+	}
+
+	return im.convertInto(nil)
+}
+
+// ConvertToImageInto - то же, что ConvertToImage без анаглифа, но результат записывается в dst, если это
+// изображение той же глубины цвета, в которое помещается буфер. Лишние строки и столбцы dst не меняются. Иначе
+// создается новое изображение на все строки, под которые выделен буфер: у полосы, перенесенной MoveBand, их
+// может быть больше текущих. Так тайловый рендер не выделяет изображение для каждой полосы заново.
+func (im *ImageMatrix) ConvertToImageInto(dst image.Image) image.Image {
+	if dst == nil {
+		dst, _ = im.outputImage(nil, im.Resolution.Width, cap(im.Pixels))
+	}
+
+	return im.convertInto(dst)
+}
+
+// convertInto - записывает буфер в dst или, если он не подходит, в новое изображение по размеру буфера.
+func (im *ImageMatrix) convertInto(dst image.Image) image.Image {
+	img, set := im.outputImage(dst, im.Resolution.Width, im.Resolution.Height)
+
+	for y, row := range im.Pixels {
+		for x := range row {
+			set(x, y, im.outputColour(row[x].Colour, y))
+		}
+	}
+
+	return img
+}
+
+// outputImage - изображение результата width x height с глубиной цвета BitDepth и функция, которая квантует
+// в него цвет без умножения на альфа-канал. Если dst того же типа и вмещает width x height, используется
+// он, иначе создается новое изображение. В FloatImage альфа-канала нет, и цвет записывается как есть.
+func (im *ImageMatrix) outputImage(dst image.Image, width, height int) (img image.Image, set func(x, y int, c FloatColour)) {
+	rect := image.Rect(0, 0, width, height)
+
+	switch im.BitDepth {
+	case FloatBitDepth:
+		out, ok := dst.(*FloatImage)
+		if !ok || !rect.In(out.Rect) {
+			out = NewFloatImage(rect)
+		}
+
+		return out, func(x, y int, c FloatColour) {
+			out.SetFloat(x, y, float32(c[0]), float32(c[1]), float32(c[2]))
+		}
+	case 16:
+		out, ok := dst.(*image.NRGBA64)
+		if !ok || !rect.In(out.Rect) {
+			out = image.NewNRGBA64(rect)
+		}
+
+		return out, func(x, y int, c FloatColour) {
+			out.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(quantize(c[0], math.MaxUint16)),
+				G: uint16(quantize(c[1], math.MaxUint16)),
+				B: uint16(quantize(c[2], math.MaxUint16)),
+				A: uint16(quantize(c[3], math.MaxUint16)),
+			})
+		}
+	}
+
+	out, ok := dst.(*image.NRGBA)
+	if !ok || !rect.In(out.Rect) {
+		out = image.NewNRGBA(rect)
+	}
+
+	return out, func(x, y int, c FloatColour) {
+		out.SetNRGBA(x, y, color.NRGBA{
+			R: uint8(quantize(c[0], math.MaxUint8)),
+			G: uint8(quantize(c[1], math.MaxUint8)),
+			B: uint8(quantize(c[2], math.MaxUint8)),
+			A: uint8(quantize(c[3], math.MaxUint8)),
+		})
+	}
+}
+
+// quantize - переводит компоненту из [0; 1] в целое от 0 до maxValue с округлением.
+func quantize(v, maxValue float64) float64 {
+	return math.Round(math.Min(math.Max(v, 0), 1) * maxValue)
+}
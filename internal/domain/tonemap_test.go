@@ -0,0 +1,159 @@
+package domain_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/savers"
+)
+
+// darkGradient - темный градиент в строку шириной width: пиксель x получает x+1 попаданий цвета 16/255,
+// поэтому после тональной компрессии яркость плавно растет от 0 до 6%.
+func darkGradient(width int) *domain.ImageMatrix {
+	im := domain.NewImageMatrix(width, 1, 1, 1)
+	xform := &domain.AffineTransformation{TransformationColour: color.RGBA{R: 16, G: 16, B: 16, A: 255}}
+
+	for x := 0; x < width; x++ {
+		for i := 0; i <= x; i++ {
+			im.UpdatePixel(0, x, xform)
+		}
+	}
+
+	im.ToneMap(2.2)
+
+	return im
+}
+
+// banding - число различных уровней красного канала в строке и длина самой длинной полосы одинаковых пикселей.
+func banding(img image.Image) (levels, longestBand int) {
+	seen := make(map[uint32]bool)
+	band, longestBand := 0, 0
+
+	var previous uint32
+
+	for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+		r, _, _, _ := img.At(x, 0).RGBA()
+
+		if r == previous {
+			band++
+		} else {
+			band = 1
+		}
+
+		previous = r
+		seen[r] = true
+		longestBand = max(longestBand, band)
+	}
+
+	return len(seen), longestBand
+}
+
+func TestConvertToImage_SixteenBitRemovesBandingInDarkGradient(t *testing.T) {
+	const width = 512
+
+	im := darkGradient(width)
+
+	levels8, band8 := banding(im.ConvertToImage())
+	if band8 < 32 {
+		t.Fatalf("8-bit gradient has %d levels and bands of %d pixels, the gradient is not dark enough to band", levels8, band8)
+	}
+
+	im.BitDepth = 16
+
+	img := im.ConvertToImage()
+	if _, ok := img.(*image.NRGBA64); !ok {
+		t.Fatalf("16-bit output is %T, want *image.NRGBA64", img)
+	}
+
+	levels16, band16 := banding(img)
+	if levels16 < width*9/10 || band16 > 2 {
+		t.Errorf("16-bit gradient has %d levels and bands of %d pixels, 8-bit has %d levels and bands of %d pixels",
+			levels16, band16, levels8, band8)
+	}
+}
+
+func TestPngSaver_EncodesSixteenBits(t *testing.T) {
+	im := darkGradient(64)
+	im.BitDepth = 16
+
+	name := filepath.Join(t.TempDir(), "gradient")
+	if err := (&savers.PngSaver{BitDepth: 16}).Save(im.ConvertToImage(), name, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(name + ".png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.ColorModel() != color.RGBA64Model && decoded.ColorModel() != color.NRGBA64Model {
+		t.Errorf("decoded PNG has colour model %T, want 16 bits per channel", decoded.ColorModel())
+	}
+}
+
+func TestConvertToImage_FloatOutputKeepsLinearValues(t *testing.T) {
+	im := darkGradient(4)
+	im.BitDepth = domain.FloatBitDepth
+	im.ToneMap(1)
+
+	img, ok := im.ConvertToImage().(*domain.FloatImage)
+	if !ok {
+		t.Fatalf("float output is %T, want *domain.FloatImage", im.ConvertToImage())
+	}
+
+	// Пиксель x получил x+1 попаданий, без гаммы яркость равна log(x+1) / log(4).
+	for x := 0; x < 4; x++ {
+		want := 16.0 / 255 * math.Log(float64(x+1)) / math.Log(4)
+		if r, _, _ := img.FloatAt(x, 0); math.Abs(float64(r)-want) > 1e-6 {
+			t.Errorf("pixel %d: linear value %g, want %g", x, r, want)
+		}
+	}
+}
+
+func TestToneMapLinear_KeepsValuesAboveOneInPfm(t *testing.T) {
+	im := domain.NewImageMatrix(4, 1, 1, 1)
+	im.BitDepth = domain.FloatBitDepth
+	im.Background = &domain.Background{Top: color.RGBA{R: 32, G: 32, B: 32, A: 255}, Bottom: color.RGBA{R: 32, G: 32, B: 32, A: 255}}
+	white := &domain.AffineTransformation{TransformationColour: color.RGBA{R: 255, G: 255, B: 255, A: 255}}
+
+	for i := 0; i < 7; i++ {
+		im.UpdatePixel(0, 1, white)
+	}
+
+	im.UpdatePixel(0, 0, white)
+
+	// 8 попаданий на 4 пикселя: 2 на пиксель, поэтому пиксели с 1 и 7 попаданиями получают 0.5 и 3.5,
+	// а первый из них закрывает фон только наполовину.
+	im.ToneMapLinear(1)
+
+	var buf bytes.Buffer
+	if err := savers.EncodePfm(&buf, im.ConvertToImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := savers.DecodePfm(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float32{0.5 + 0.5*32.0/255, 3.5, 32.0 / 255, 32.0 / 255}
+
+	for x, w := range want {
+		if r, g, b := decoded.FloatAt(x, 0); math.Abs(float64(r-w)) > 1e-6 || g != r || b != r {
+			t.Errorf("pixel %d is (%v, %v, %v) in the PFM, want %v", x, r, g, b, w)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package transformations
 
-import "math"
+import (
+	"math"
+	"math/rand/v2"
+)
 
-func Spherical(x, y float64) (newX, newY float64) {
+func Spherical(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := x*x + y*y
 	if r == 0 {
 		return 0, 0 // Защита от деления на 0
@@ -11,14 +14,14 @@ func Spherical(x, y float64) (newX, newY float64) {
 	return x / r, y / r
 }
 
-func Sinusoidal(x, y float64) (newX, newY float64) {
+func Sinusoidal(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	newX = math.Sin(x * math.Pi)
 	newY = math.Sin(y * math.Pi)
 
 	return
 }
 
-func Handkerchief(x, y float64) (newX, newY float64) {
+func Handkerchief(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt((x * x) + (y * y))
 	theta := math.Atan2(y, x)
 
@@ -28,7 +31,7 @@ func Handkerchief(x, y float64) (newX, newY float64) {
 	return
 }
 
-func Swirl(x, y float64) (newX, newY float64) {
+func Swirl(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt(x*x + y*y)
 	newX = x*math.Sin(r*r) - y*math.Cos(r*r)
 	newY = x*math.Cos(r*r) - y*math.Sin(r*r)
@@ -36,7 +39,7 @@ func Swirl(x, y float64) (newX, newY float64) {
 	return
 }
 
-func Horseshoe(x, y float64) (newX, newY float64) {
+func Horseshoe(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt(x*x + y*y)
 	if r == 0 {
 		return 0, 0
@@ -48,7 +51,7 @@ func Horseshoe(x, y float64) (newX, newY float64) {
 	return
 }
 
-func Polar(x, y float64) (newX, newY float64) {
+func Polar(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt(x*x + y*y)
 	theta := math.Atan2(y, x)
 	newX = theta / math.Pi
@@ -57,7 +60,7 @@ func Polar(x, y float64) (newX, newY float64) {
 	return
 }
 
-func Disc(x, y float64) (newX, newY float64) {
+func Disc(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt(x*x + y*y)
 	theta := math.Atan2(y, x)
 	newX = theta / math.Pi * math.Sin(math.Pi*r)
@@ -66,7 +69,7 @@ func Disc(x, y float64) (newX, newY float64) {
 	return
 }
 
-func Heart(x, y float64) (newX, newY float64) {
+func Heart(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt(x*x + y*y)
 	theta := math.Atan2(y, x)
 	newX = r * math.Sin(theta*r)
@@ -75,14 +78,42 @@ func Heart(x, y float64) (newX, newY float64) {
 	return
 }
 
-func Linear(x, y float64) (newX, newY float64) {
+func Linear(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	return x, y
 }
 
-func EyeFish(x, y float64) (newX, newY float64) {
+func EyeFish(_ *rand.Rand, x, y float64) (newX, newY float64) {
 	r := math.Sqrt(x*x + y*y)
 	newX = 2.0 / (r + 1) * x
 	newY = 2.0 / (r + 1) * y
 
 	return
 }
+
+// PreBlur - гауссово размытие как pre_blur в JWildfire: точка сдвигается в случайном направлении
+// на расстояние с приближенно нормальным распределением (сумма четырех равномерных чисел минус 2).
+func PreBlur(rng *rand.Rand, x, y float64) (newX, newY float64) {
+	r := rng.Float64() + rng.Float64() + rng.Float64() + rng.Float64() - 2
+	angle := 2 * math.Pi * rng.Float64()
+
+	return x + r*math.Cos(angle), y + r*math.Sin(angle)
+}
+
+// Параметры Curl: в реестре нет параметров преобразований, поэтому коэффициенты фиксированы.
+const (
+	curlC1 = 0.5
+	curlC2 = 0.25
+)
+
+// Curl - преобразование curl из flam3 с коэффициентами curlC1 и curlC2.
+func Curl(_ *rand.Rand, x, y float64) (newX, newY float64) {
+	re := 1 + curlC1*x + curlC2*(x*x-y*y)
+	im := curlC1*y + 2*curlC2*x*y
+
+	r := re*re + im*im
+	if r == 0 {
+		return 0, 0
+	}
+
+	return (x*re + y*im) / r, (y*re - x*im) / r
+}
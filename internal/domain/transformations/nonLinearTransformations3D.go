@@ -0,0 +1,47 @@
+package transformations
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// julia3DPower - степень преобразования Julia3D.
+const julia3DPower = 2
+
+func Linear3D(_ *rand.Rand, x, y, z float64) (newX, newY, newZ float64) {
+	return x, y, z
+}
+
+func Spherical3D(_ *rand.Rand, x, y, z float64) (newX, newY, newZ float64) {
+	r := x*x + y*y + z*z
+	if r == 0 {
+		return 0, 0, 0 // Защита от деления на 0
+	}
+
+	return x / r, y / r, z / r
+}
+
+func Julia3D(rng *rand.Rand, x, y, z float64) (newX, newY, newZ float64) {
+	z /= julia3DPower
+	planar := x*x + y*y
+	r := math.Pow(planar+z*z, (1.0/julia3DPower-1)*0.5)
+	rPlanar := r * math.Sqrt(planar)
+
+	branch := float64(rng.IntN(julia3DPower))
+	angle := (math.Atan2(y, x) + 2*math.Pi*branch) / julia3DPower
+
+	newX = rPlanar * math.Cos(angle)
+	newY = rPlanar * math.Sin(angle)
+	newZ = r * z
+
+	return
+}
+
+func Bubble(_ *rand.Rand, x, y, _ float64) (newX, newY, newZ float64) {
+	r := (x*x+y*y)/4 + 1
+	newX = x / r
+	newY = y / r
+	newZ = 2/r - 1
+
+	return
+}
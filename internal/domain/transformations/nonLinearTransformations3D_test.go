@@ -0,0 +1,50 @@
+package transformations_test
+
+import (
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+const tolerance = 1e-9
+
+func TestSpherical3D_InvertsRadius(t *testing.T) {
+	x, y, z := transformations.Spherical3D(nil, 1, 2, 2)
+	if math.Abs(x-1.0/9) > tolerance || math.Abs(y-2.0/9) > tolerance || math.Abs(z-2.0/9) > tolerance {
+		t.Errorf("Spherical3D(1, 2, 2) = (%v, %v, %v), want (1/9, 2/9, 2/9)", x, y, z)
+	}
+
+	if x, y, z := transformations.Spherical3D(nil, 0, 0, 0); x != 0 || y != 0 || z != 0 {
+		t.Errorf("Spherical3D of the origin = (%v, %v, %v), want the origin", x, y, z)
+	}
+}
+
+func TestJulia3D_TakesSquareRoot(t *testing.T) {
+	rng := random.New(1, 0)
+
+	for i := 0; i < 20; i++ {
+		x, y, z := transformations.Julia3D(rng, 3, 4, 0)
+
+		// Квадрат результата на плоскости возвращает исходную точку (3, 4).
+		if z != 0 || math.Abs(x*x-y*y-3) > tolerance || math.Abs(2*x*y-4) > tolerance {
+			t.Fatalf("Julia3D(3, 4, 0) = (%v, %v, %v) is not a square root of (3, 4)", x, y, z)
+		}
+	}
+}
+
+func TestBubble_MapsPlaneToUnitSphere(t *testing.T) {
+	for _, p := range [][2]float64{{0, 0}, {1, 2}, {-3, 0.5}, {10, -10}} {
+		x, y, z := transformations.Bubble(nil, p[0], p[1], 7)
+		if r := x*x + y*y + z*z; math.Abs(r-1) > tolerance {
+			t.Errorf("Bubble(%v, %v) = (%v, %v, %v) is %v from the origin, want the unit sphere", p[0], p[1], x, y, z, r)
+		}
+	}
+}
+
+func TestLinear3D_KeepsPoint(t *testing.T) {
+	if x, y, z := transformations.Linear3D(nil, 0.1, -0.2, 0.3); x != 0.1 || y != -0.2 || z != 0.3 {
+		t.Errorf("Linear3D changed the point to (%v, %v, %v)", x, y, z)
+	}
+}
@@ -0,0 +1,40 @@
+package transformations_test
+
+import (
+	"math"
+	"testing"
+
+	"FractalFlame/internal/domain/transformations"
+	"FractalFlame/pkg/random"
+)
+
+func TestPreBlur_MovesPointByBoundedOffset(t *testing.T) {
+	rng := random.New(3, 0)
+	moved := false
+
+	for i := 0; i < 100; i++ {
+		x, y := transformations.PreBlur(rng, 1, -1)
+
+		// Смещение не больше 2: сумма четырех чисел из [0; 1) минус 2.
+		if math.Hypot(x-1, y+1) > 2 {
+			t.Fatalf("PreBlur(1, -1) = (%v, %v) moved the point by more than 2", x, y)
+		}
+
+		moved = moved || x != 1 || y != -1
+	}
+
+	if !moved {
+		t.Error("PreBlur never moved the point")
+	}
+}
+
+func TestCurl_KeepsOriginAndMatchesFormula(t *testing.T) {
+	if x, y := transformations.Curl(nil, 0, 0); x != 0 || y != 0 {
+		t.Errorf("Curl of the origin = (%v, %v), want the origin", x, y)
+	}
+
+	// re = 1 + 0.5 + 0.25 = 1.75, im = 0, поэтому Curl(1, 0) = (1 / 1.75, 0).
+	if x, y := transformations.Curl(nil, 1, 0); math.Abs(x-1/1.75) > tolerance || math.Abs(y) > tolerance {
+		t.Errorf("Curl(1, 0) = (%v, %v), want (%v, 0)", x, y, 1/1.75)
+	}
+}
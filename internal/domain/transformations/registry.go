@@ -0,0 +1,119 @@
+package transformations
+
+import (
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+
+	"FractalFlame/internal/domain/errors"
+)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]func(rng *rand.Rand, x, y float64) (newX, newY float64){
+		"Spherical":    Spherical,
+		"Sinusoidal":   Sinusoidal,
+		"Handkerchief": Handkerchief,
+		"Swirl":        Swirl,
+		"Horseshoe":    Horseshoe,
+		"Polar":        Polar,
+		"Disc":         Disc,
+		"Heart":        Heart,
+		"Linear":       Linear,
+		"EyeFish":      EyeFish,
+		"Curl":         Curl,
+		// Имена в стиле JWildfire для списков pre и post генома.
+		"pre_blur":      PreBlur,
+		"pre_spherical": Spherical,
+		"post_curl":     Curl,
+	}
+	registry3D = map[string]func(rng *rand.Rand, x, y, z float64) (newX, newY, newZ float64){
+		"Linear3D":    Linear3D,
+		"Spherical3D": Spherical3D,
+		"Julia3D":     Julia3D,
+		"Bubble":      Bubble,
+	}
+	custom = map[string]func(rng *rand.Rand, x, y float64) (newX, newY float64){}
+)
+
+// Register - добавляет нелинейное преобразование в реестр под указанным именем. Имя не может быть пустым
+// или начинаться и заканчиваться пробелами, встроенные и уже зарегистрированные преобразования переопределить
+// нельзя.
+func Register(name string, fn func(rng *rand.Rand, x, y float64) (newX, newY float64)) error {
+	if name == "" || strings.TrimSpace(name) != name {
+		return errors.ErrInvalidTransformationName{Name: name}
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	_, exists := registry[name]
+	_, exists3D := registry3D[name]
+	_, existsCustom := custom[name]
+
+	if exists || exists3D || existsCustom {
+		return errors.ErrDuplicateTransformation{Name: name}
+	}
+
+	custom[name] = fn
+
+	return nil
+}
+
+// Unregister - удаляет из реестра преобразование, добавленное через Register. Встроенные преобразования
+// не удаляются.
+func Unregister(name string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	delete(custom, name)
+}
+
+// Lookup - возвращает преобразование из реестра по имени.
+func Lookup(name string) (fn func(rng *rand.Rand, x, y float64) (newX, newY float64), ok bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	if fn, ok = registry[name]; ok {
+		return fn, ok
+	}
+
+	fn, ok = custom[name]
+
+	return fn, ok
+}
+
+// Lookup3D - возвращает трехмерное преобразование из реестра по имени.
+func Lookup3D(name string) (fn func(rng *rand.Rand, x, y, z float64) (newX, newY, newZ float64), ok bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	fn, ok = registry3D[name]
+
+	return fn, ok
+}
+
+// Names - возвращает отсортированный список имен всех зарегистрированных преобразований, двумерных
+// и трехмерных.
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry)+len(registry3D)+len(custom))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	for name := range custom {
+		names = append(names, name)
+	}
+
+	for name := range registry3D {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
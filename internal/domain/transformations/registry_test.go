@@ -0,0 +1,61 @@
+package transformations_test
+
+import (
+	"errors"
+	"testing"
+
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/transformations"
+)
+
+func TestRegister_RejectsInvalidNames(t *testing.T) {
+	for _, name := range []string{"", " ", "\t", " Spiral", "Spiral "} {
+		err := transformations.Register(name, transformations.Linear)
+
+		var invalid domainErrors.ErrInvalidTransformationName
+		if !errors.As(err, &invalid) {
+			t.Errorf("Register(%q) = %v, want ErrInvalidTransformationName", name, err)
+		}
+	}
+}
+
+func TestRegister_RejectsBuiltinNames(t *testing.T) {
+	for _, name := range []string{"Linear", "Bubble"} {
+		err := transformations.Register(name, transformations.Linear)
+
+		var duplicate domainErrors.ErrDuplicateTransformation
+		if !errors.As(err, &duplicate) {
+			t.Errorf("Register(%q) = %v, want ErrDuplicateTransformation", name, err)
+		}
+	}
+}
+
+func TestUnregister_AllowsRegisteringAgain(t *testing.T) {
+	if err := transformations.Register("TestCustom", transformations.Linear); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := transformations.Register("TestCustom", transformations.Linear); err == nil {
+		t.Error("second Register with the same name succeeded")
+	}
+
+	transformations.Unregister("TestCustom")
+
+	if _, ok := transformations.Lookup("TestCustom"); ok {
+		t.Error("unregistered transformation is still found")
+	}
+
+	if err := transformations.Register("TestCustom", transformations.Linear); err != nil {
+		t.Errorf("Register after Unregister: %v", err)
+	}
+
+	transformations.Unregister("TestCustom")
+}
+
+func TestUnregister_KeepsBuiltins(t *testing.T) {
+	transformations.Unregister("Linear")
+
+	if _, ok := transformations.Lookup("Linear"); !ok {
+		t.Error("Unregister removed a builtin transformation")
+	}
+}
@@ -0,0 +1,94 @@
+package domain
+
+import "math/rand/v2"
+
+// TransformFunc3D - трехмерное нелинейное преобразование, случайные числа берутся из генератора рабочего потока.
+type TransformFunc3D func(rng *rand.Rand, x, y, z float64) (newX, newY, newZ float64)
+
+// Variation - нелинейное преобразование с весом в составе аффинного преобразования. Если задана Func3D,
+// используется она, иначе двумерная Func, а координата z проходит без изменений.
+type Variation struct {
+	Name   string
+	Weight float64
+	Func   TransformFunc
+	Func3D TransformFunc3D
+}
+
+// apply - применяет преобразование без учета веса.
+func (v *Variation) apply(rng *rand.Rand, x, y, z float64) (newX, newY, newZ float64) {
+	if v.Func3D != nil {
+		return v.Func3D(rng, x, y, z)
+	}
+
+	newX, newY = v.Func(rng, x, y)
+
+	return newX, newY, z
+}
+
+// applyVariations - применяет к точке нелинейную часть преобразования в порядке pre, основная смесь, post.
+// Pre- и post-преобразования применяются последовательно и заменяют точку на Weight * V(x, y), основные
+// преобразования складываются с весами. Координата z складывается только из трехмерных преобразований,
+// двумерные оставляют ее без изменений. Если у преобразования нет собственных основных преобразований,
+// используется случайное из общего набора NonLinearTransformations.
+func (im *ImageMatrix) applyVariations(rng *rand.Rand, tr *AffineTransformation, x, y, z float64) (newX, newY, newZ float64) {
+	for i := range tr.PreVariations {
+		v := &tr.PreVariations[i]
+		x, y, z = v.apply(rng, x, y, z)
+		x, y = v.Weight*x, v.Weight*y
+
+		if v.Func3D != nil {
+			z *= v.Weight
+		}
+	}
+
+	if len(tr.Variations) == 0 {
+		x, y = im.GetNonLinearTransform(rng, x, y)
+	} else {
+		var (
+			sumX, sumY, sumZ float64
+			has3D            bool
+		)
+
+		for i := range tr.Variations {
+			v := &tr.Variations[i]
+			vx, vy, vz := v.apply(rng, x, y, z)
+			sumX += v.Weight * vx
+			sumY += v.Weight * vy
+
+			if v.Func3D != nil {
+				sumZ += v.Weight * vz
+				has3D = true
+			}
+		}
+
+		if !has3D {
+			sumZ = z
+		}
+
+		x, y, z = sumX, sumY, sumZ
+	}
+
+	for i := range tr.PostVariations {
+		v := &tr.PostVariations[i]
+		x, y, z = v.apply(rng, x, y, z)
+		x, y = v.Weight*x, v.Weight*y
+
+		if v.Func3D != nil {
+			z *= v.Weight
+		}
+	}
+
+	return x, y, z
+}
+
+// NeedsNonLinearTransformations - сообщает, есть ли преобразования без собственных основных нелинейных
+// преобразований, которым нужен общий набор NonLinearTransformations.
+func (im *ImageMatrix) NeedsNonLinearTransformations() bool {
+	for i := range im.LinearTransformations {
+		if len(im.LinearTransformations[i].Variations) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,152 @@
+package domain_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	"FractalFlame/internal/domain/transformations"
+)
+
+// constantFlame - пламя 200x200 из одного преобразования, аффинная часть которого переводит любую точку
+// в (c, f). При камере по умолчанию точка (x, y) попадает в пиксель (100 + 100x, 100 + 100y).
+func constantFlame(c, f float64) (*domain.ImageMatrix, *domain.AffineTransformation) {
+	im := domain.NewImageMatrix(200, 200, 1, 50)
+	im.LinearTransformations = []domain.AffineTransformation{{C: c, F: -f}}
+
+	return im, &im.LinearTransformations[0]
+}
+
+// hitPixels - координаты пикселей, в которые было хотя бы одно попадание.
+func hitPixels(im *domain.ImageMatrix) [][2]int {
+	var hits [][2]int
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			if im.Pixels[y][x].HitRate > 0 {
+				hits = append(hits, [2]int{x, y})
+			}
+		}
+	}
+
+	return hits
+}
+
+func shift(dx, dy float64) domain.TransformFunc {
+	return func(_ *rand.Rand, x, y float64) (newX, newY float64) {
+		return x + dx, y + dy
+	}
+}
+
+func scale(k float64) domain.TransformFunc {
+	return func(_ *rand.Rand, x, y float64) (newX, newY float64) {
+		return k * x, k * y
+	}
+}
+
+func TestIterate_PlotsPointAfterPreMainAndPostVariations(t *testing.T) {
+	im, tr := constantFlame(0.1, 0.2)
+	tr.PreVariations = []domain.Variation{{Name: "shift", Weight: 1, Func: shift(0.2025, 0)}}
+	tr.Variations = []domain.Variation{
+		{Name: "Linear", Weight: 0.5, Func: transformations.Linear},
+		{Name: "triple", Weight: 0.5, Func: scale(3)},
+	}
+	tr.PostVariations = []domain.Variation{{Name: "shift", Weight: 1, Func: shift(0, -0.505)}}
+
+	renderPoints(im, 0, 1)
+
+	// (0.1, 0.2) -> pre (0.3025, 0.2) -> 0.5*p + 0.5*3p = (0.605, 0.4) -> post (0.605, -0.105).
+	hits := hitPixels(im)
+	if len(hits) != 1 || hits[0] != [2]int{160, 89} {
+		t.Errorf("hit pixels %v, want only (160, 89)", hits)
+	}
+}
+
+func TestIterate_WithoutVariationsPlotsSharedNonLinearTransformation(t *testing.T) {
+	im, _ := constantFlame(0.1, 0.2)
+	im.NonLinearTransformations = append(im.NonLinearTransformations, scale(-2.05))
+
+	renderPoints(im, 0, 1)
+
+	// Отрисовывается результат нелинейного преобразования (-0.205, -0.41), а не аффинной части (0.1, 0.2).
+	hits := hitPixels(im)
+	if len(hits) != 1 || hits[0] != [2]int{79, 59} {
+		t.Errorf("hit pixels %v, want only (79, 59)", hits)
+	}
+}
+
+func TestIterate_PostAffineShiftMovesHistogram(t *testing.T) {
+	plain, tr := constantFlame(0.105, 0.205)
+	tr.Variations = []domain.Variation{{Name: "Linear", Weight: 1, Func: transformations.Linear}}
+
+	shifted, shiftedTr := constantFlame(0.105, 0.205)
+	shiftedTr.Variations = tr.Variations
+	shiftedTr.Post = &domain.PostAffine{A: 1, C: 0.3, D: 1, F: 0.1}
+
+	renderPoints(plain, 0, 1)
+	renderPoints(shifted, 0, 1)
+
+	if hits := hitPixels(plain); len(hits) != 1 || hits[0] != [2]int{110, 120} {
+		t.Errorf("hit pixels without post-affine %v, want only (110, 120)", hits)
+	}
+
+	if hits := hitPixels(shifted); len(hits) != 1 || hits[0] != [2]int{140, 110} {
+		t.Errorf("hit pixels with post-affine shift %v, want only (140, 110)", hits)
+	}
+}
+
+func TestIterate_IdentityPostAffineKeepsHistogram(t *testing.T) {
+	plain := histogramFlame(5)
+	identity := histogramFlame(5)
+
+	for i := range identity.LinearTransformations {
+		post := domain.IdentityPostAffine
+		identity.LinearTransformations[i].Post = &post
+	}
+
+	renderPoints(plain, 0, plain.StartingPoints)
+	renderPoints(identity, 0, identity.StartingPoints)
+
+	for y := range plain.Pixels {
+		for x := range plain.Pixels[y] {
+			if plain.Pixels[y][x].HitRate != identity.Pixels[y][x].HitRate {
+				t.Fatalf("pixel (%d, %d) differs with the identity post-affine", x, y)
+			}
+		}
+	}
+}
+
+// sideCamera - камера, которая смотрит на пламя сбоку: точка (x, y, z) проецируется в (x, -z).
+func sideCamera() *domain.Camera3D {
+	return domain.NewCamera3D(90, 0, 0, 0)
+}
+
+func TestApplyVariations_TwoDimensionalVariationsPassZThrough(t *testing.T) {
+	im, tr := constantFlame(0.21, 0)
+	im.Camera3D = sideCamera()
+	tr.Z = &domain.ZAffine{D: 0.305}
+	tr.Variations = []domain.Variation{{Name: "Linear", Weight: 0.5, Func: transformations.Linear}}
+
+	renderPoints(im, 0, 1)
+
+	if hits := hitPixels(im); len(hits) != 1 || hits[0] != [2]int{110, 69} {
+		t.Errorf("hit pixels %v, want only (110, 69) with z = 0.305 unchanged", hits)
+	}
+}
+
+func TestApplyVariations_ZBlendsOnlyThreeDimensionalVariations(t *testing.T) {
+	im, tr := constantFlame(0.1025, 0)
+	im.Camera3D = sideCamera()
+	tr.Z = &domain.ZAffine{D: 0.605}
+	tr.Variations = []domain.Variation{
+		{Name: "Linear3D", Weight: 0.5, Func3D: transformations.Linear3D},
+		{Name: "triple", Weight: 0.5, Func: scale(3)},
+	}
+
+	renderPoints(im, 0, 1)
+
+	// x = 0.5x + 1.5x = 0.205, z = 0.5z = 0.3025: двумерное преобразование в z не участвует.
+	if hits := hitPixels(im); len(hits) != 1 || hits[0] != [2]int{120, 69} {
+		t.Errorf("hit pixels %v, want only (120, 69)", hits)
+	}
+}
@@ -0,0 +1,236 @@
+package domain
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/transformations"
+)
+
+// isometry - движение плоскости x' = a*x + b*y + tx, y' = c*x + d*y + ty, сдвиг (tx, ty) задан в долях
+// векторов решетки.
+type isometry struct {
+	a, b, c, d float64
+	tx, ty     float64
+}
+
+type wallpaperGroup struct {
+	basis      [2][2]float64
+	generators []isometry
+}
+
+var (
+	sqrt3Half = math.Sqrt(3) / 2
+
+	obliqueLattice     = [2][2]float64{{1, 0}, {0.25, 0.9}}
+	rectangularLattice = [2][2]float64{{1, 0}, {0, 0.75}}
+	squareLattice      = [2][2]float64{{1, 0}, {0, 1}}
+	hexagonalLattice   = [2][2]float64{{1, 0}, {0.5, sqrt3Half}}
+
+	halfTurn      = isometry{a: -1, d: -1}
+	quarterTurn   = isometry{b: -1, c: 1}
+	thirdTurn     = isometry{a: -0.5, b: -sqrt3Half, c: sqrt3Half, d: -0.5}
+	sixthTurn     = isometry{a: 0.5, b: -sqrt3Half, c: sqrt3Half, d: 0.5}
+	mirrorX       = isometry{a: -1, d: 1}
+	mirrorY       = isometry{a: 1, d: -1}
+	centring      = isometry{a: 1, d: 1, tx: 0.5, ty: 0.5}
+	glideY        = isometry{a: -1, d: 1, ty: 0.5}
+	glideMirrorX  = isometry{a: -1, d: 1, tx: 0.5}
+	glideDiagonal = isometry{a: -1, d: 1, tx: 0.5, ty: 0.5}
+	diagonalP4g   = isometry{b: -1, c: -1, tx: 0.5, ty: 0.5}
+)
+
+// wallpaperGroups - 17 групп симметрии обоев: решетка и образующие точечной группы (с учетом скольжений).
+var wallpaperGroups = map[string]wallpaperGroup{
+	"p1":   {basis: obliqueLattice},
+	"p2":   {basis: obliqueLattice, generators: []isometry{halfTurn}},
+	"pm":   {basis: rectangularLattice, generators: []isometry{mirrorX}},
+	"pg":   {basis: rectangularLattice, generators: []isometry{glideY}},
+	"cm":   {basis: rectangularLattice, generators: []isometry{mirrorX, centring}},
+	"pmm":  {basis: rectangularLattice, generators: []isometry{mirrorX, mirrorY}},
+	"pmg":  {basis: rectangularLattice, generators: []isometry{halfTurn, glideMirrorX}},
+	"pgg":  {basis: rectangularLattice, generators: []isometry{halfTurn, glideDiagonal}},
+	"cmm":  {basis: rectangularLattice, generators: []isometry{mirrorX, mirrorY, centring}},
+	"p4":   {basis: squareLattice, generators: []isometry{quarterTurn}},
+	"p4m":  {basis: squareLattice, generators: []isometry{quarterTurn, mirrorX}},
+	"p4g":  {basis: squareLattice, generators: []isometry{quarterTurn, diagonalP4g}},
+	"p3":   {basis: hexagonalLattice, generators: []isometry{thirdTurn}},
+	"p3m1": {basis: hexagonalLattice, generators: []isometry{thirdTurn, mirrorX}},
+	"p31m": {basis: hexagonalLattice, generators: []isometry{thirdTurn, mirrorY}},
+	"p6":   {basis: hexagonalLattice, generators: []isometry{sixthTurn}},
+	"p6m":  {basis: hexagonalLattice, generators: []isometry{sixthTurn, mirrorY}},
+}
+
+// wallpaper - активная группа симметрии обоев: решетка с учетом масштаба и диапазон ячеек, покрывающих кадр.
+type wallpaper struct {
+	name       string
+	scale      float64
+	basis      [2][2]float64
+	inverse    [2][2]float64
+	cellsFrom  [2]int
+	cellsCount [2]int
+}
+
+// SetWallpaperGroup - заполняет плоскость пламенем с симметрией одной из 17 групп обоев (p1, p2, pm, pg, cm,
+// pmm, pmg, pgg, cmm, p4, p4m, p4g, p3, p3m1, p31m, p6, p6m). Движения группы добавляются как преобразования
+// симметрии, участвующие в игре хаоса, точки после каждого шага сворачиваются в ячейку решетки, а при
+// отрисовке переносятся в случайную ячейку кадра. scale задает размер ячейки решетки.
+func (im *ImageMatrix) SetWallpaperGroup(name string, scale float64) error {
+	group, ok := wallpaperGroups[name]
+	if !ok {
+		return errors.ErrUnknownWallpaperGroup{Name: name}
+	}
+
+	if scale <= 0 {
+		scale = 1
+	}
+
+	w := &wallpaper{name: name, scale: scale}
+
+	for i := range group.basis {
+		w.basis[i] = [2]float64{group.basis[i][0] * scale, group.basis[i][1] * scale}
+	}
+
+	det := w.basis[0][0]*w.basis[1][1] - w.basis[1][0]*w.basis[0][1]
+	w.inverse = [2][2]float64{
+		{w.basis[1][1] / det, -w.basis[0][1] / det},
+		{-w.basis[1][0] / det, w.basis[0][0] / det},
+	}
+
+	for _, element := range group.closeGroup() {
+		tx, ty := w.toCartesian(element.tx, element.ty)
+
+		im.LinearTransformations = append(im.LinearTransformations, AffineTransformation{
+			A:          element.a,
+			B:          element.b,
+			C:          tx,
+			D:          element.d,
+			E:          element.c,
+			F:          -ty,
+			Variations: []Variation{{Name: "Linear", Weight: 1, Func: transformations.Linear}},
+			Symmetry:   true,
+		})
+	}
+
+	im.wallpaper = w
+	width, height := im.frame()
+	w.updateCells(&im.view, width, height)
+
+	return nil
+}
+
+// WallpaperGroup - возвращает имя и масштаб активной группы обоев, пустое имя означает, что режим выключен.
+func (im *ImageMatrix) WallpaperGroup() (name string, scale float64) {
+	if im.wallpaper == nil {
+		return "", 0
+	}
+
+	return im.wallpaper.name, im.wallpaper.scale
+}
+
+// closeGroup - строит все нетождественные элементы группы по образующим, сдвиги берутся по модулю решетки.
+func (g wallpaperGroup) closeGroup() []isometry {
+	identity := isometry{a: 1, d: 1}
+	elements := []isometry{identity}
+	seen := map[[6]int64]bool{identity.key(): true}
+
+	for i := 0; i < len(elements); i++ {
+		for _, generator := range g.generators {
+			next := g.compose(generator, elements[i])
+			if key := next.key(); !seen[key] {
+				seen[key] = true
+				elements = append(elements, next)
+			}
+		}
+	}
+
+	return elements[1:]
+}
+
+// compose - возвращает движение outer∘inner, сдвиг результата приводится в ячейку [0; 1) решетки.
+func (g wallpaperGroup) compose(outer, inner isometry) isometry {
+	innerX := inner.tx*g.basis[0][0] + inner.ty*g.basis[1][0]
+	innerY := inner.tx*g.basis[0][1] + inner.ty*g.basis[1][1]
+	outerX := outer.tx*g.basis[0][0] + outer.ty*g.basis[1][0]
+	outerY := outer.tx*g.basis[0][1] + outer.ty*g.basis[1][1]
+
+	x := outer.a*innerX + outer.b*innerY + outerX
+	y := outer.c*innerX + outer.d*innerY + outerY
+
+	det := g.basis[0][0]*g.basis[1][1] - g.basis[1][0]*g.basis[0][1]
+	u := (x*g.basis[1][1] - y*g.basis[1][0]) / det
+	v := (y*g.basis[0][0] - x*g.basis[0][1]) / det
+
+	return isometry{
+		a:  outer.a*inner.a + outer.b*inner.c,
+		b:  outer.a*inner.b + outer.b*inner.d,
+		c:  outer.c*inner.a + outer.d*inner.c,
+		d:  outer.c*inner.b + outer.d*inner.d,
+		tx: fraction(u),
+		ty: fraction(v),
+	}
+}
+
+// fraction - дробная часть числа, значения, отличающиеся от целого на погрешность вычислений, дают 0.
+func fraction(v float64) float64 {
+	f := v - math.Floor(v+1e-9)
+	if f < 0 {
+		return 0
+	}
+
+	return f
+}
+
+func (g isometry) key() [6]int64 {
+	const precision = 1e6
+
+	return [6]int64{
+		int64(math.Round(g.a * precision)), int64(math.Round(g.b * precision)),
+		int64(math.Round(g.c * precision)), int64(math.Round(g.d * precision)),
+		int64(math.Round(g.tx * precision)), int64(math.Round(g.ty * precision)),
+	}
+}
+
+func (w *wallpaper) toCartesian(u, v float64) (x, y float64) {
+	return u*w.basis[0][0] + v*w.basis[1][0], u*w.basis[0][1] + v*w.basis[1][1]
+}
+
+func (w *wallpaper) toLattice(x, y float64) (u, v float64) {
+	return x*w.inverse[0][0] + y*w.inverse[1][0], x*w.inverse[0][1] + y*w.inverse[1][1]
+}
+
+// fold - сворачивает точку в ячейку решетки, содержащую начало координат.
+func (w *wallpaper) fold(x, y float64) (foldedX, foldedY float64) {
+	u, v := w.toLattice(x, y)
+
+	return w.toCartesian(u-math.Floor(u), v-math.Floor(v))
+}
+
+// place - переносит свернутую точку в случайную ячейку решетки из покрывающих кадр.
+func (w *wallpaper) place(rng *rand.Rand, x, y float64) (placedX, placedY float64) {
+	i := float64(w.cellsFrom[0] + rng.IntN(w.cellsCount[0]))
+	j := float64(w.cellsFrom[1] + rng.IntN(w.cellsCount[1]))
+	dx, dy := w.toCartesian(i, j)
+
+	return x + dx, y + dy
+}
+
+// updateCells - находит диапазон ячеек решетки, которые пересекают кадр камеры.
+func (w *wallpaper) updateCells(v *view, width, height int) {
+	low := [2]float64{math.Inf(1), math.Inf(1)}
+	high := [2]float64{math.Inf(-1), math.Inf(-1)}
+
+	for _, corner := range [][2]float64{{0, 0}, {float64(width), 0}, {0, float64(height)}, {float64(width), float64(height)}} {
+		x, y := v.toWorld(corner[0], corner[1])
+		u, t := w.toLattice(x, y)
+
+		low = [2]float64{math.Min(low[0], u), math.Min(low[1], t)}
+		high = [2]float64{math.Max(high[0], u), math.Max(high[1], t)}
+	}
+
+	for i := range low {
+		w.cellsFrom[i] = int(math.Floor(low[i]))
+		w.cellsCount[i] = int(math.Floor(high[i])) - w.cellsFrom[i] + 1
+	}
+}
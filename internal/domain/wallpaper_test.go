@@ -0,0 +1,133 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"FractalFlame/internal/domain"
+	domainErrors "FractalFlame/internal/domain/errors"
+	"FractalFlame/internal/domain/generator"
+	"FractalFlame/internal/domain/transformations"
+)
+
+func TestSetWallpaperGroup_AddsWholeGroup(t *testing.T) {
+	orders := map[string]int{
+		"p1": 1, "p2": 2, "pm": 2, "pg": 2, "cm": 4, "pmm": 4, "pmg": 4, "pgg": 4, "cmm": 8,
+		"p4": 4, "p4m": 8, "p4g": 8, "p3": 3, "p3m1": 6, "p31m": 6, "p6": 6, "p6m": 12,
+	}
+
+	for name, order := range orders {
+		im := domain.NewImageMatrix(32, 32, 1, 1)
+		before := len(im.LinearTransformations)
+
+		if err := im.SetWallpaperGroup(name, 0.5); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if got := len(im.LinearTransformations) - before; got != order-1 {
+			t.Errorf("%s: got %d symmetry xforms, want %d", name, got, order-1)
+		}
+
+		if group, scale := im.WallpaperGroup(); group != name || scale != 0.5 {
+			t.Errorf("%s: WallpaperGroup() = %q, %v", name, group, scale)
+		}
+	}
+}
+
+func TestSetWallpaperGroup_UnknownGroup(t *testing.T) {
+	im := domain.NewImageMatrix(32, 32, 1, 1)
+
+	err := im.SetWallpaperGroup("p5", 1)
+	if !errors.As(err, &domainErrors.ErrUnknownWallpaperGroup{}) {
+		t.Errorf("got %v, want ErrUnknownWallpaperGroup", err)
+	}
+}
+
+// renderWallpaper - рендерит пламя с группой обоев name и ячейкой 0.5 в кадр 200x200 многопоточным генератором
+// по iterations итераций на стартовую точку. Ячейка решетки занимает 50 пикселей, а начало координат, центр
+// поворотов группы, приходится на угол пикселей (100, 100).
+func renderWallpaper(t *testing.T, name string, iterations int) *domain.ImageMatrix {
+	t.Helper()
+
+	im := domain.NewImageMatrix(200, 200, 16, iterations)
+	im.Seed = 5
+	im.GenerateAffineTransformations()
+	im.NonLinearTransformations = append(im.NonLinearTransformations, transformations.Linear)
+
+	if err := im.SetWallpaperGroup(name, 0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	(&generator.MultiThreadGenerator{NumWorkers: 4}).Render(context.Background(), im)
+
+	return im
+}
+
+// matchedHits - доля пикселей с попаданиями, образ которых при отображении move тоже получил попадания. Учитываются
+// только пиксели, образ которых остается в кадре. Сравнивается множество попаданий, а не плотность: аттрактор
+// переходит в себя при движениях группы, а плотность в игре хаоса вместе с обычными преобразованиями - нет.
+func matchedHits(im *domain.ImageMatrix, move func(x, y int) (int, int)) float64 {
+	var total, matched int
+
+	for y := range im.Pixels {
+		for x := range im.Pixels[y] {
+			mx, my := move(x, y)
+			if im.Pixels[y][x].HitRate == 0 || mx < 0 || my < 0 || mx >= 200 || my >= 200 {
+				continue
+			}
+
+			total++
+
+			if im.Pixels[my][mx].HitRate > 0 {
+				matched++
+			}
+		}
+	}
+
+	return float64(matched) / float64(max(total, 1))
+}
+
+func TestWallpaperGroup_RenderIsInvariantUnderLatticeAndPointGroup(t *testing.T) {
+	// Ячейка - 50 пикселей, движения отсчитываются от угла пикселей (100, 100).
+	translate := func(x, y int) (int, int) { return x + 50, y }
+	quarterTurn := func(x, y int) (int, int) { return 199 - y, x }
+	halfTurn := func(x, y int) (int, int) { return 199 - x, 199 - y }
+	mirror := func(x, y int) (int, int) { return 199 - x, y }
+
+	tc := []struct {
+		group     string
+		operation string
+		move      func(x, y int) (int, int)
+		invariant bool
+	}{
+		{group: "p1", operation: "lattice translation", move: translate, invariant: true},
+		{group: "p4", operation: "lattice translation", move: translate, invariant: true},
+		{group: "p4", operation: "quarter turn", move: quarterTurn, invariant: true},
+		{group: "p2", operation: "half turn", move: halfTurn, invariant: true},
+		{group: "pmm", operation: "mirror", move: mirror, invariant: true},
+		// В p1 нет поворотов и отражений: проверка должна это заметить, иначе она ничего не доказывает.
+		{group: "p1", operation: "quarter turn", move: quarterTurn, invariant: false},
+		{group: "p1", operation: "mirror", move: mirror, invariant: false},
+	}
+
+	renders := map[string]*domain.ImageMatrix{}
+
+	for _, c := range tc {
+		im, ok := renders[c.group]
+		if !ok {
+			im = renderWallpaper(t, c.group, 320000)
+			renders[c.group] = im
+		}
+
+		matched := matchedHits(im, c.move)
+
+		if c.invariant && matched < 0.95 {
+			t.Errorf("%s: only %.3f of hit pixels are hit after a %s", c.group, matched, c.operation)
+		}
+
+		if !c.invariant && matched > 0.8 {
+			t.Errorf("%s: %.3f of hit pixels are hit after a %s that is not in the group", c.group, matched, c.operation)
+		}
+	}
+}
@@ -4,22 +4,75 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"sync"
 
 	"FractalFlame/internal/domain/errors"
 )
 
 type Output struct {
-	w      io.Writer
-	logger slog.Logger
+	w        io.Writer
+	logger   slog.Logger
+	mutex    sync.Mutex
+	live     bool
+	terminal bool
 }
 
 func NewWriter(w io.Writer, logger *slog.Logger) *Output {
-	return &Output{w: w, logger: *logger}
+	return &Output{w: w, logger: *logger, terminal: isTerminal(w)}
+}
+
+// isTerminal - проверяет, что w - терминал, а не файл или канал.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 func (o *Output) Write(messages ...interface{}) {
-	message := fmt.Sprintln(messages...)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.finishLine()
+	o.write(fmt.Sprintln(messages...))
+}
+
+// Progress - выводит строку состояния поверх предыдущей: курсор возвращается в начало строки, а ее остаток
+// стирается. Следующий вызов Write начнется с новой строки. Если вывод не терминал, строка состояния
+// не выводится, чтобы управляющие последовательности не попадали в файлы и каналы.
+func (o *Output) Progress(line string) {
+	if !o.terminal {
+		return
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.write("\r" + line + "\x1b[K")
+	o.live = true
+}
+
+// FinishProgress - завершает строку состояния переводом строки, если она выведена.
+func (o *Output) FinishProgress() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.finishLine()
+}
+
+func (o *Output) finishLine() {
+	if o.live {
+		o.live = false
+		o.write("\n")
+	}
+}
 
+func (o *Output) write(message string) {
 	_, err := o.w.Write([]byte(message))
 	if err != nil {
 		o.logger.Error("output error occurred", errors.ErrOutPut{}.Error(), err)
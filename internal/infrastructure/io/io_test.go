@@ -0,0 +1,22 @@
+package io_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"FractalFlame/internal/infrastructure/io"
+)
+
+func TestOutput_ProgressIsSkippedWhenNotATerminal(t *testing.T) {
+	var buf bytes.Buffer
+
+	out := io.NewWriter(&buf, slog.Default())
+	out.Progress("Rendering: 1/2 starting points")
+	out.FinishProgress()
+	out.Write("done")
+
+	if buf.String() != "done\n" {
+		t.Errorf("output %q, want only the message without the status line", buf.String())
+	}
+}
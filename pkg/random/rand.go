@@ -5,18 +5,42 @@ import (
 	"math/rand/v2"
 )
 
+// WorkerRand - генератор случайных чисел рабочего потока. Перед обработкой очередной стартовой точки он
+// переинициализируется от seed и номера точки, поэтому результат не зависит от того, какой поток ее обработал.
+type WorkerRand struct {
+	*rand.Rand
+	source *rand.PCG
+}
+
+// NewWorkerRand - создает генератор для рабочего потока.
+func NewWorkerRand() *WorkerRand {
+	source := rand.NewPCG(0, 0)
+
+	return &WorkerRand{Rand: rand.New(source), source: source}
+}
+
+// Reseed - переинициализирует генератор для потока stream при заданном seed.
+func (w *WorkerRand) Reseed(seed, stream uint64) {
+	w.source.Seed(seed, stream)
+}
+
+// New - создает детерминированный генератор для потока stream при заданном seed.
+func New(seed, stream uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, stream))
+}
+
 // GenerateRandFloat64 позволяет получить значение в формате типа float64, из диапазона [-1;1].
-func GenerateRandFloat64() float64 {
-	n := rand.Float64() //nolint
+func GenerateRandFloat64(rng *rand.Rand) float64 {
+	n := rng.Float64()
 
 	return n*2 - 1
 }
 
 // GenerateRandomColor - функция, которая генерирует случайный цвет в цветовой модели RGBA.
-func GenerateRandomColor() color.RGBA {
+func GenerateRandomColor(rng *rand.Rand) color.RGBA {
 	return color.RGBA{
-		R: byte(rand.IntN(255)), //nolint
-		G: byte(rand.IntN(255)), //nolint
-		B: byte(rand.IntN(255)), //nolint
+		R: byte(rng.IntN(255)),
+		G: byte(rng.IntN(255)),
+		B: byte(rng.IntN(255)),
 		A: 255}
 }